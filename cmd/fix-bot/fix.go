@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+
+	"github.com/github-vet/bots/cmd/vet-bot/driver"
+	"github.com/github-vet/bots/cmd/vet-bot/fixes"
+	"github.com/github-vet/bots/cmd/vet-bot/loopclosure"
+	"github.com/github-vet/bots/cmd/vet-bot/looppointer"
+	"github.com/github-vet/bots/internal/db"
+	"github.com/github-vet/bots/internal/snippet"
+	"golang.org/x/tools/go/analysis"
+)
+
+var (
+	// ErrStaleSnippet is returned when the snippet found in a file does not match the snippet quoted in its finding.
+	ErrStaleSnippet = errors.New("snippet does not match the quote recorded for the finding")
+	// ErrNoFix is returned when no fix is suggested for the loop in which a finding was made.
+	ErrNoFix = errors.New("no fix was suggested for the finding")
+)
+
+// fixAnalyzers lists the analyzers whose suggested fixes are applied by fixbot.
+var fixAnalyzers = []*analysis.Analyzer{
+	loopclosure.Analyzer,
+	looppointer.Analyzer,
+}
+
+// Fix describes the changes made to a single file to fix a confirmed finding.
+type Fix struct {
+	Path     string   // the slash-separated path of the file within its repository
+	Original []byte   // the contents of the file before the fix was applied
+	Fixed    []byte   // the contents of the file after the fix was applied
+	Patch    string   // a unified diff describing the fix
+	Messages []string // the messages of each diagnostic which was fixed
+}
+
+// PrepareFix applies the fixes suggested for the provided finding to contents, which should be the contents of the
+// file in which the finding was made, as of the commit in which it was found. The snippet quoted in the finding is
+// regenerated first, to make sure the file is the one which was analyzed. Only diagnostics which span exactly the
+// lines of the finding are fixed.
+//
+// The fixed file is not formatted with gofmt, so that lines of the file which are unrelated to the fix are left as
+// they were found.
+func PrepareFix(finding db.Finding, contents []byte) (Fix, error) {
+	if snippet.Quote(contents, finding.StartLine, finding.EndLine) != finding.Quote {
+		return Fix{}, ErrStaleSnippet
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, finding.Filepath, contents, parser.AllErrors)
+	if err != nil {
+		return Fix{}, fmt.Errorf("cannot parse %s: %w", finding.Filepath, err)
+	}
+
+	var merged []fixes.Edit
+	var messages []string
+	outcomes, err := driver.Run(fset, []*ast.File{file}, nil, fixAnalyzers, func(d analysis.Diagnostic) {
+		if len(d.SuggestedFixes) == 0 || fset.Position(d.Pos).Line != finding.StartLine || fset.Position(d.End).Line != finding.EndLine {
+			return
+		}
+		_, edits, err := fixes.Edits(fset, d.SuggestedFixes[0])
+		if err != nil {
+			log.Printf("cannot apply fix suggested at %v: %v", fset.Position(d.Pos), err)
+			return
+		}
+		candidate, skipped := fixes.Merge([][]fixes.Edit{merged, edits})
+		if skipped > 0 {
+			return // overlaps with the fix for a diagnostic reported by another analyzer.
+		}
+		merged = candidate
+		messages = append(messages, d.Message)
+	})
+	if err != nil {
+		return Fix{}, err
+	}
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			log.Printf("failed %s analysis: %v", outcome.Analyzer.Name, outcome.Err)
+		}
+	}
+	if len(merged) == 0 {
+		return Fix{}, ErrNoFix
+	}
+
+	fixed, err := fixes.Apply(contents, merged)
+	if err != nil {
+		return Fix{}, err
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), finding.Filepath, fixed, parser.AllErrors); err != nil {
+		return Fix{}, fmt.Errorf("fixed file does not parse: %w", err)
+	}
+	patch, err := fixes.Diff(finding.Filepath, contents, merged)
+	if err != nil {
+		return Fix{}, err
+	}
+	return Fix{
+		Path:     finding.Filepath,
+		Original: contents,
+		Fixed:    fixed,
+		Patch:    patch,
+		Messages: messages,
+	}, nil
+}
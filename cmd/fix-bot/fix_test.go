@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/github-vet/bots/internal/db"
+	"github.com/github-vet/bots/internal/snippet"
+	"github.com/stretchr/testify/assert"
+)
+
+const fanOutSrc = `package sample
+
+func fanOut(xs []int, done chan bool) {
+	for _, x := range xs {
+		go func() {
+			println(x)
+			done <- true
+		}()
+	}
+}
+`
+
+const fanOutPatch = `--- a/sample/fan.go
++++ b/sample/fan.go
+@@ -2,6 +2,7 @@
+ 
+ func fanOut(xs []int, done chan bool) {
+ 	for _, x := range xs {
++		x := x
+ 		go func() {
+ 			println(x)
+ 			done <- true
+`
+
+func fanOutFinding() db.Finding {
+	return db.Finding{
+		ID:           7,
+		GithubOwner:  "owner",
+		GithubRepo:   "repo",
+		Filepath:     "sample/fan.go",
+		RootCommitID: "abc123",
+		Quote:        snippet.Quote([]byte(fanOutSrc), 4, 9),
+		StartLine:    4,
+		EndLine:      9,
+		Message:      "range-loop variable x used in defer or goroutine at line 6",
+	}
+}
+
+func TestPrepareFix(t *testing.T) {
+	fix, err := PrepareFix(fanOutFinding(), []byte(fanOutSrc))
+	assert.NoError(t, err)
+	assert.Equal(t, "sample/fan.go", fix.Path)
+	assert.Equal(t, fanOutPatch, fix.Patch)
+	assert.Contains(t, string(fix.Fixed), "\t\tx := x\n\t\tgo func() {")
+	assert.Equal(t, []string{"range-loop variable x used in defer or goroutine at line 6"}, fix.Messages)
+}
+
+func TestPrepareFixStaleSnippet(t *testing.T) {
+	finding := fanOutFinding()
+	finding.Quote = "for _, y := range ys {\n}\n"
+	_, err := PrepareFix(finding, []byte(fanOutSrc))
+	assert.Equal(t, ErrStaleSnippet, err)
+}
+
+func TestPrepareFixNoFix(t *testing.T) {
+	finding := fanOutFinding()
+	finding.StartLine, finding.EndLine = 5, 8
+	finding.Quote = snippet.Quote([]byte(fanOutSrc), 5, 8)
+	_, err := PrepareFix(finding, []byte(fanOutSrc))
+	assert.Equal(t, ErrNoFix, err)
+}
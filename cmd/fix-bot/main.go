@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/github-vet/bots/internal/db"
+	"github.com/github-vet/bots/internal/ratelimit"
+	"github.com/google/go-github/v32/github"
+	"golang.org/x/oauth2"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// BugAssessment is the expert assessment given to findings which experts have confirmed to be bugs.
+const BugAssessment = "-1"
+
+// main runs fixbot.
+//
+// fixbot reads every finding which experts agree is a bug from the database written by vetbot. For each finding, it
+// downloads the file in which the finding was made as of the commit in which it was found, checks that the snippet
+// quoted in the finding is unchanged, and applies the fix suggested by the analyzers which reported it. It then
+// forks the original repository, commits the fix to a new branch of the fork, and opens a pull request against the
+// original repository which links back to the issue in which experts confirmed the finding.
+//
+// fixbot expects an environment variable named GITHUB_TOKEN which contains a valid personal access token used
+// to authenticate with the GitHub API.
+//
+// If -dry-run is passed, fixbot only reads from GitHub. The patch and pull request prepared for each finding are
+// written to the directory passed via -out instead.
+func main() {
+	opts, err := parseOpts()
+	if err != nil {
+		log.Fatalf("error during config: %v", err)
+	}
+	log.Printf("configured options: %+v", opts)
+
+	bot, err := NewFixBot(opts)
+	if err != nil {
+		log.Fatalf("error creating fixbot: %v", err)
+	}
+	defer bot.Close()
+
+	confirmed, err := ConfirmedFindings(&bot)
+	if err != nil {
+		log.Fatalf("cannot read confirmed findings: %v", err)
+	}
+	fixed := 0
+	for _, cf := range confirmed {
+		if err := FixFinding(&bot, cf); err != nil {
+			log.Printf("cannot fix finding %d: %v", cf.Finding.ID, err)
+			continue
+		}
+		fixed++
+	}
+	log.Printf("prepared fixes for %d of %d confirmed findings; performed %d API calls", fixed, len(confirmed), bot.client.GetCount())
+}
+
+// FixBot wraps the GitHub client and database used by fixbot.
+type FixBot struct {
+	client *ratelimit.Client
+	db     *sql.DB
+	opts   opts
+}
+
+// NewFixBot creates a new bot using the provided options.
+func NewFixBot(opts opts) (FixBot, error) {
+	ctx := context.Background()
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: opts.GithubToken},
+	)
+	client := github.NewClient(oauth2.NewClient(ctx, ts))
+	if opts.GithubURL != "" {
+		baseURL, err := url.Parse(strings.TrimSuffix(opts.GithubURL, "/") + "/")
+		if err != nil {
+			return FixBot{}, fmt.Errorf("cannot parse GitHub URL %s: %w", opts.GithubURL, err)
+		}
+		client.BaseURL = baseURL
+	}
+	limited, err := ratelimit.NewClient(ctx, client)
+	if err != nil {
+		return FixBot{}, err
+	}
+
+	DB, err := sql.Open("sqlite3", opts.DatabaseFile)
+	if err != nil {
+		return FixBot{}, fmt.Errorf("cannot open database from %s: %w", opts.DatabaseFile, err)
+	}
+	return FixBot{
+		client: &limited,
+		db:     DB,
+		opts:   opts,
+	}, nil
+}
+
+// Close closes any open database connections.
+func (fb *FixBot) Close() {
+	fb.db.Close()
+}
+
+// ConfirmedFinding pairs a finding with the issue in which experts confirmed it.
+type ConfirmedFinding struct {
+	Finding db.Finding
+	Issue   db.Issue
+}
+
+// IssueLink returns a link to the issue in which experts confirmed the finding.
+func (cf ConfirmedFinding) IssueLink() string {
+	return fmt.Sprintf("https://github.com/%s/%s/issues/%d", cf.Issue.GithubOwner, cf.Issue.GithubRepo, cf.Issue.GithubID)
+}
+
+// Permalink returns the GitHub permalink which refers to the snippet of code quoted in the finding.
+func (cf ConfirmedFinding) Permalink() string {
+	f := cf.Finding
+	return fmt.Sprintf("https://github.com/%s/%s/blob/%s/%s#L%d-L%d", f.GithubOwner, f.GithubRepo, f.RootCommitID,
+		strings.ReplaceAll(f.Filepath, " ", "%20"), f.StartLine, f.EndLine)
+}
+
+// ConfirmedFindings lists every finding which experts agree is a bug.
+func ConfirmedFindings(bot *FixBot) ([]ConfirmedFinding, error) {
+	ctx := context.Background()
+	findings, err := db.FindingDAO.ListByExpertAssessment(ctx, bot.db, BugAssessment)
+	if err != nil {
+		return nil, err
+	}
+	var result []ConfirmedFinding
+	for _, finding := range findings {
+		issue, err := db.IssueDAO.FindByFinding(ctx, bot.db, finding.ID)
+		if err != nil {
+			return nil, fmt.Errorf("cannot find issue for finding %d: %w", finding.ID, err)
+		}
+		result = append(result, ConfirmedFinding{Finding: finding, Issue: issue})
+	}
+	return result, nil
+}
+
+// FixFinding prepares a fix for the provided finding, and either opens a pull request for it or writes it to disk,
+// if -dry-run was passed.
+func FixFinding(bot *FixBot, cf ConfirmedFinding) error {
+	file, contents, err := DownloadFile(bot, cf.Finding)
+	if err != nil {
+		return err
+	}
+	fix, err := PrepareFix(cf.Finding, contents)
+	if err != nil {
+		return err
+	}
+	pr, err := NewPullRequest(cf, fix, file.GetSHA())
+	if err != nil {
+		return err
+	}
+	if bot.opts.DryRun {
+		return WritePullRequest(bot.opts.OutputDir, pr)
+	}
+	return OpenPullRequest(bot, pr)
+}
+
+// DownloadFile downloads the file in which the provided finding was made, as of the commit in which it was found.
+func DownloadFile(bot *FixBot, finding db.Finding) (*github.RepositoryContent, []byte, error) {
+	opt := github.RepositoryContentGetOptions{Ref: finding.RootCommitID}
+	file, _, _, err := bot.client.GetContents(finding.GithubOwner, finding.GithubRepo, finding.Filepath, &opt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot download %s: %w", finding.Filepath, err)
+	}
+	if file == nil {
+		return nil, nil, errors.New(finding.Filepath + " is not a file")
+	}
+	contents, err := file.GetContent()
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot decode %s: %w", finding.Filepath, err)
+	}
+	return file, []byte(contents), nil
+}
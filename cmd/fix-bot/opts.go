@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+type opts struct {
+	GithubToken  string
+	GithubURL    string
+	DatabaseFile string
+	ForkOrg      string
+	DryRun       bool
+	OutputDir    string
+}
+
+// OptSchema defines a configuration option which can come either from the command-line or
+// environment variables.
+type OptSchema struct {
+	// EnvArgName is the name of the environment variable used for this option.
+	EnvArgName string
+	// FlagName is the name of a command-line flag used for this option.
+	FlagName string
+	// FlagUsage is the usage description sent from the flags package for this option.
+	FlagUsage string
+	// DefaultValue is the value used if no override is given. Empty-string is used for required arguments.
+	DefaultValue string
+	// Required is true if the value must be set during config.
+	Required bool
+	// OptSetter is a function run to set the value of the option in the provided opts struct
+	OptSetter func(o *opts, value string) error
+	// Value is a temporary storage location for values read from the flag package.
+	Value string
+}
+
+var optSchemas []OptSchema = []OptSchema{
+	{"GITHUB_TOKEN", "token", "GitHub access token", "", true,
+		func(o *opts, value string) error { o.GithubToken = value; return nil }, ""},
+	{"GITHUB_URL", "github-url", "base URL of the GitHub API; useful for testing against a fake server", "", false,
+		func(o *opts, value string) error { o.GithubURL = value; return nil }, ""},
+	{"DATABASE_FILE", "db", "path to database sqlite3 file written by vetbot", "", true,
+		func(o *opts, value string) error { o.DatabaseFile = value; return nil }, ""},
+	{"FORK_ORG", "fork-org", "organization into which repositories are forked; the account of the GitHub token is used if empty", "", false,
+		func(o *opts, value string) error { o.ForkOrg = value; return nil }, ""},
+	{"DRY_RUN", "dry-run", "if 'true', write each patch and pull request to disk instead of opening it on GitHub", "false", false,
+		func(o *opts, value string) error {
+			boolValue, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("could not parse dry-run flag %s as boolean", value)
+			}
+			o.DryRun = boolValue
+			return nil
+		}, ""},
+	{"OUTPUT_DIR", "out", "directory into which patches and pull requests are written during a dry-run", "fixes", false,
+		func(o *opts, value string) error { o.OutputDir = value; return nil }, ""},
+}
+
+func parseOpts() (opts, error) {
+	result := opts{}
+	for i := 0; i < len(optSchemas); i++ {
+		flag.StringVar(&optSchemas[i].Value, optSchemas[i].FlagName, optSchemas[i].DefaultValue, optSchemas[i].FlagUsage)
+	}
+	flag.Parse()
+	for _, schema := range optSchemas {
+		value, ok := os.LookupEnv(schema.EnvArgName)
+		if !ok {
+			value = schema.Value
+		}
+		if value == "" {
+			if schema.Required {
+				return opts{}, fmt.Errorf("no configured value for required option '%s'", schema.EnvArgName)
+			}
+			continue
+		}
+		if err := schema.OptSetter(&result, value); err != nil {
+			return opts{}, err
+		}
+	}
+	return result, nil
+}
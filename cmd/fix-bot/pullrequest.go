@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// PullRequest describes a pull request which fixes a confirmed finding in its original repository.
+type PullRequest struct {
+	ConfirmedFinding
+	Fix
+	FileSHA string // the SHA of the blob of the file at the root commit of the finding
+	Branch  string
+	Title   string
+	Body    string
+}
+
+// BranchName returns the name of the branch onto which the fix for the provided finding is committed.
+func BranchName(findingID int64) string {
+	return fmt.Sprintf("github-vet/fix-%d", findingID)
+}
+
+// NewPullRequest describes a pull request which applies the provided fix for the provided finding.
+func NewPullRequest(cf ConfirmedFinding, fix Fix, fileSHA string) (PullRequest, error) {
+	pr := PullRequest{
+		ConfirmedFinding: cf,
+		Fix:              fix,
+		FileSHA:          fileSHA,
+		Branch:           BranchName(cf.Finding.ID),
+		Title:            fmt.Sprintf("Fix reference to a loop variable in %s", fix.Path),
+	}
+	var b strings.Builder
+	if err := bodyTemplate.Execute(&b, pr); err != nil {
+		return PullRequest{}, err
+	}
+	pr.Body = b.String()
+	return pr, nil
+}
+
+var bodyTemplate = template.Must(template.New("body").Parse(bodyTemplateStr))
+
+const bodyTemplateStr = `This pull request fixes a bug in [{{.Finding.Filepath}}]({{.Permalink}}), which was found by [github-vet](https://github.com/github-vet/bots) and confirmed by experts in {{.IssueLink}}.
+
+The bug refers to a loop variable after the iteration in which it was declared may have ended. In Go, loop variables are shared between iterations, so the reference may observe a value assigned by a later iteration.
+{{range .Messages}}
+> {{.}}
+{{end}}
+The fix below was generated automatically from the commit {{.Finding.RootCommitID}}; please review it with care before merging.
+
+~~~diff
+{{.Patch}}~~~
+`
+
+// WritePullRequest writes the patch and the description of the provided pull request to dir, in place of opening it.
+func WritePullRequest(dir string, pr PullRequest) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	base := filepath.Join(dir, fmt.Sprintf("fix-%d", pr.Finding.ID))
+	if err := ioutil.WriteFile(base+".patch", []byte(pr.Patch), 0644); err != nil {
+		return err
+	}
+	description := fmt.Sprintf("# %s\n\nRepository: %s/%s\nBranch: %s\n\n%s", pr.Title, pr.Finding.GithubOwner, pr.Finding.GithubRepo, pr.Branch, pr.Body)
+	if err := ioutil.WriteFile(base+".md", []byte(description), 0644); err != nil {
+		return err
+	}
+	log.Printf("wrote fix for finding %d to %s.patch", pr.Finding.ID, base)
+	return nil
+}
+
+// forkRetries and forkRetryDelay control how often fixbot checks whether GitHub has finished creating a fork.
+var (
+	forkRetries    = 5
+	forkRetryDelay = 5 * time.Second
+)
+
+// OpenPullRequest forks the repository in which the finding was made, commits the fix to a new branch of the fork
+// based on the root commit of the finding, and opens a pull request against the default branch of the original
+// repository.
+func OpenPullRequest(bot *FixBot, pr PullRequest) error {
+	owner, repo := pr.Finding.GithubOwner, pr.Finding.GithubRepo
+	upstream, _, err := bot.client.GetRepository(owner, repo)
+	if err != nil {
+		return fmt.Errorf("cannot get repository %s/%s: %w", owner, repo, err)
+	}
+	fork, _, err := bot.client.CreateFork(owner, repo, &github.RepositoryCreateForkOptions{Organization: bot.opts.ForkOrg})
+	if _, ok := err.(*github.AcceptedError); err != nil && !ok {
+		return fmt.Errorf("cannot fork %s/%s: %w", owner, repo, err)
+	}
+	forkOwner, forkRepo := fork.GetOwner().GetLogin(), fork.GetName()
+
+	// the fork is created in the background, so creating the branch may fail until it is ready.
+	ref := "refs/heads/" + pr.Branch
+	for i := 0; ; i++ {
+		_, resp, err := bot.client.CreateRef(forkOwner, forkRepo, &github.Reference{
+			Ref:    &ref,
+			Object: &github.GitObject{SHA: &pr.Finding.RootCommitID},
+		})
+		if err == nil {
+			break
+		}
+		if resp != nil && resp.StatusCode == 422 {
+			return fmt.Errorf("branch %s already exists in %s/%s: %w", pr.Branch, forkOwner, forkRepo, err)
+		}
+		if i+1 >= forkRetries {
+			return fmt.Errorf("cannot create branch %s in %s/%s: %w", pr.Branch, forkOwner, forkRepo, err)
+		}
+		time.Sleep(forkRetryDelay)
+	}
+
+	_, _, err = bot.client.UpdateFile(forkOwner, forkRepo, pr.Path, &github.RepositoryContentFileOptions{
+		Message: &pr.Title,
+		Content: pr.Fixed,
+		SHA:     &pr.FileSHA,
+		Branch:  &pr.Branch,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot commit fix to %s/%s: %w", forkOwner, forkRepo, err)
+	}
+
+	head := forkOwner + ":" + pr.Branch
+	base := upstream.GetDefaultBranch()
+	modify := true
+	opened, _, err := bot.client.CreatePullRequest(owner, repo, &github.NewPullRequest{
+		Title:               &pr.Title,
+		Head:                &head,
+		Base:                &base,
+		Body:                &pr.Body,
+		MaintainerCanModify: &modify,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot open pull request against %s/%s: %w", owner, repo, err)
+	}
+	log.Printf("opened pull request %s for finding %d", opened.GetHTMLURL(), pr.Finding.ID)
+	return nil
+}
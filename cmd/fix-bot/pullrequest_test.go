@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/github-vet/bots/internal/db"
+	"github.com/github-vet/bots/internal/ratelimit"
+	"github.com/google/go-github/v32/github"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGithub serves the subset of the GitHub API used by fixbot, and records the requests which change anything.
+type fakeGithub struct {
+	mut      sync.Mutex
+	requests map[string]map[string]interface{} // the decoded body of each write request, keyed by method and path
+}
+
+func (fg *fakeGithub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		fg.mut.Lock()
+		fg.requests[r.Method+" "+r.URL.Path] = body
+		fg.mut.Unlock()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Method + " " + r.URL.Path {
+	case "GET /repos/owner/repo/contents/sample/fan.go":
+		if r.URL.Query().Get("ref") != "abc123" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"type": "file", "encoding": "base64", "sha": "blob456", "path": "sample/fan.go", "content": %q}`,
+			base64.StdEncoding.EncodeToString([]byte(fanOutSrc)))
+	case "GET /repos/owner/repo":
+		fmt.Fprint(w, `{"name": "repo", "default_branch": "main"}`)
+	case "POST /repos/owner/repo/forks":
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, `{"name": "repo", "owner": {"login": "vetbot"}}`)
+	case "POST /repos/vetbot/repo/git/refs":
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"ref": "refs/heads/github-vet/fix-7"}`)
+	case "PUT /repos/vetbot/repo/contents/sample/fan.go":
+		fmt.Fprint(w, `{}`)
+	case "POST /repos/owner/repo/pulls":
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"number": 1, "html_url": "https://github.com/owner/repo/pull/1"}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestBot(t *testing.T, opts opts) (*FixBot, *fakeGithub) {
+	fake := &fakeGithub{requests: make(map[string]map[string]interface{})}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	limited, err := ratelimit.NewClient(context.Background(), client)
+	assert.NoError(t, err)
+	return &FixBot{client: &limited, opts: opts}, fake
+}
+
+func fanOutConfirmed() ConfirmedFinding {
+	return ConfirmedFinding{
+		Finding: fanOutFinding(),
+		Issue: db.Issue{
+			FindingID:        7,
+			GithubOwner:      "github-vet",
+			GithubRepo:       "findings",
+			GithubID:         42,
+			ExpertAssessment: BugAssessment,
+		},
+	}
+}
+
+func TestFixFindingDryRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fix-bot")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	bot, fake := newTestBot(t, opts{DryRun: true, OutputDir: dir})
+
+	assert.NoError(t, FixFinding(bot, fanOutConfirmed()))
+	assert.Empty(t, fake.requests)
+
+	patch, err := ioutil.ReadFile(filepath.Join(dir, "fix-7.patch"))
+	assert.NoError(t, err)
+	assert.Equal(t, fanOutPatch, string(patch))
+
+	description, err := ioutil.ReadFile(filepath.Join(dir, "fix-7.md"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(description), "# Fix reference to a loop variable in sample/fan.go\n")
+	assert.Contains(t, string(description), "Repository: owner/repo\nBranch: github-vet/fix-7\n")
+	assert.Contains(t, string(description), "https://github.com/github-vet/findings/issues/42")
+	assert.Contains(t, string(description), "https://github.com/owner/repo/blob/abc123/sample/fan.go#L4-L9")
+	assert.Contains(t, string(description), "> range-loop variable x used in defer or goroutine at line 6\n")
+	assert.Contains(t, string(description), "~~~diff\n"+fanOutPatch+"~~~\n")
+}
+
+func TestFixFinding(t *testing.T) {
+	bot, fake := newTestBot(t, opts{})
+
+	assert.NoError(t, FixFinding(bot, fanOutConfirmed()))
+
+	ref := fake.requests["POST /repos/vetbot/repo/git/refs"]
+	assert.Equal(t, "refs/heads/github-vet/fix-7", ref["ref"])
+	assert.Equal(t, "abc123", ref["sha"])
+
+	update := fake.requests["PUT /repos/vetbot/repo/contents/sample/fan.go"]
+	assert.Equal(t, "blob456", update["sha"])
+	assert.Equal(t, "github-vet/fix-7", update["branch"])
+	content, err := base64.StdEncoding.DecodeString(fmt.Sprint(update["content"]))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "\t\tx := x\n")
+
+	pull := fake.requests["POST /repos/owner/repo/pulls"]
+	assert.Equal(t, "vetbot:github-vet/fix-7", pull["head"])
+	assert.Equal(t, "main", pull["base"])
+	assert.Equal(t, "Fix reference to a loop variable in sample/fan.go", pull["title"])
+	assert.Contains(t, pull["body"], "https://github.com/github-vet/findings/issues/42")
+}
+
+func TestFixFindingStaleCommit(t *testing.T) {
+	bot, fake := newTestBot(t, opts{})
+	cf := fanOutConfirmed()
+	cf.Finding.RootCommitID = "def789"
+
+	assert.Error(t, FixFinding(bot, cf))
+	assert.Empty(t, fake.requests)
+}
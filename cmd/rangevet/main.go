@@ -0,0 +1,55 @@
+// rangevet runs the range-loop analyzers used by vet-bot as a standalone tool.
+//
+// rangevet can be run directly on a set of packages, or used as a vet tool.
+//
+//	rangevet ./...
+//	go vet -vettool=$(which rangevet) ./...
+//
+// Findings in modules whose go.mod declares Go 1.22 or later are suppressed, since each iteration of a loop has its
+// own copy of the loop variables in those modules.
+//
+// The list of third-party functions which are known to be safe can be provided via the -accept flag or the
+// ACCEPT_LIST_FILE environment variable. A summary of third-party functions written by the summarize command can be
+// provided via the -summary flag or the SUMMARY_FILE environment variable.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/github-vet/bots/cmd/vet-bot/acceptlist"
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
+	"github.com/github-vet/bots/cmd/vet-bot/loopclosure"
+	"github.com/github-vet/bots/cmd/vet-bot/looppointer"
+	"github.com/github-vet/bots/cmd/vet-bot/summary"
+	"golang.org/x/tools/go/analysis/multichecker"
+)
+
+func main() {
+	// the analyzers are run over packages found on disk, along with the go.mod files which contain them.
+	if err := gomod.Analyzer.Flags.Set("read", "true"); err != nil {
+		log.Fatalf("cannot configure the gomod analyzer: %v", err)
+	}
+
+	var acceptListFlag acceptlist.Flag
+	if path, ok := os.LookupEnv("ACCEPT_LIST_FILE"); ok {
+		if err := acceptListFlag.Set(path); err != nil {
+			log.Fatalf("cannot read accept list: %v", err)
+		}
+	}
+	flag.Var(&acceptListFlag, "accept", "path to accept list YAML file")
+
+	var summaryFlag summary.Flag
+	if path, ok := os.LookupEnv("SUMMARY_FILE"); ok {
+		if err := summaryFlag.Set(path); err != nil {
+			log.Fatalf("cannot read summary: %v", err)
+		}
+	}
+	flag.Var(&summaryFlag, "summary", "path to summary YAML file written by summarize")
+
+	multichecker.Main(
+		loopclosure.Analyzer,
+		looppointer.Analyzer,
+	)
+}
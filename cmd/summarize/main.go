@@ -0,0 +1,226 @@
+// summarize computes a summary of the functions declared by third-party packages, which vet-bot and rangevet can
+// load alongside the accept list.
+//
+// summarize runs the pointerescapes and nogofunc analyzers over the source of each module it is given, and records
+// which pointer arguments of each exported function are never stored, and which functions never start a goroutine.
+// Modules can be read from a local directory, or from the module cache by passing path@version.
+//
+//	summarize -o summary.yml golang.org/x/sync@v0.1.0 ./path/to/module
+//
+// Calls from the summarized modules into other third-party code are treated conservatively, unless the function
+// called is found in the accept list or a summary passed via -accept or -summary.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/github-vet/bots/cmd/vet-bot/acceptlist"
+	"github.com/github-vet/bots/cmd/vet-bot/callgraph"
+	"github.com/github-vet/bots/cmd/vet-bot/driver"
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
+	"github.com/github-vet/bots/cmd/vet-bot/nogofunc"
+	"github.com/github-vet/bots/cmd/vet-bot/pointerescapes"
+	"github.com/github-vet/bots/cmd/vet-bot/summary"
+	"golang.org/x/tools/go/analysis"
+)
+
+func main() {
+	var acceptListFlag acceptlist.Flag
+	var summaryFlag summary.Flag
+	output := flag.String("o", "summary.yml", "path to the summary YAML file to write")
+	flag.Var(&acceptListFlag, "accept", "path to accept list YAML file")
+	flag.Var(&summaryFlag, "summary", "path to a summary YAML file describing the dependencies of the summarized modules")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] (path/to/module | module@version)...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	result := summary.New()
+	for _, arg := range flag.Args() {
+		root, err := moduleRoot(arg)
+		if err != nil {
+			log.Fatalf("cannot find module %s: %v", arg, err)
+		}
+		sum, err := summarizeDir(root)
+		if err != nil {
+			log.Fatalf("cannot summarize module %s: %v", arg, err)
+		}
+		result.Merge(sum)
+	}
+	data, err := result.Marshal()
+	if err != nil {
+		log.Fatalf("cannot marshal summary: %v", err)
+	}
+	if err := ioutil.WriteFile(*output, data, 0644); err != nil {
+		log.Fatalf("cannot write summary: %v", err)
+	}
+}
+
+// moduleRoot returns the directory containing the source of the provided module, which is either a local directory
+// or a module path and version found in the module cache.
+func moduleRoot(arg string) (string, error) {
+	if info, err := os.Stat(arg); err == nil && info.IsDir() {
+		return arg, nil
+	}
+	idx := strings.LastIndex(arg, "@")
+	if idx == -1 {
+		return "", errors.New("expected a local directory or module@version")
+	}
+	dir := filepath.Join(moduleCache(), escapePath(arg[:idx])+"@"+escapePath(arg[idx+1:]))
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("%s was not found in the module cache; try running 'go mod download %s'", dir, arg)
+	}
+	return dir, nil
+}
+
+// moduleCache returns the directory of the module cache.
+func moduleCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(os.Getenv("GOPATH"))
+	if len(gopath) == 0 || gopath[0] == "" {
+		gopath = filepath.SplitList(build.Default.GOPATH)
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+// escapePath escapes a module path or version the same way the go command does in the module cache, by replacing
+// each upper-case letter with an exclamation mark followed by the letter's lower-case equivalent.
+func escapePath(str string) string {
+	var sb strings.Builder
+	for _, r := range str {
+		if unicode.IsUpper(r) {
+			sb.WriteByte('!')
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// summarizeDir parses every Go file found beneath the provided directory, and summarizes each exported function
+// which takes a pointer argument.
+func summarizeDir(root string) (*summary.Summary, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != root && (name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		src, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		file, err := parser.ParseFile(fset, filepath.ToSlash(path), src, parser.AllErrors)
+		if err != nil {
+			log.Printf("failed to parse file %s: %v", path, err)
+			return nil
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := map[*analysis.Analyzer]interface{}{
+		gomod.Analyzer: gomod.Analyze(fset, files, gomod.ReadFile),
+	}
+	outcomes, err := driver.RunWithInputs(fset, files, nil, inputs, []*analysis.Analyzer{pointerescapes.Analyzer, nogofunc.Analyzer}, func(analysis.Diagnostic) {})
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[*analysis.Analyzer]interface{})
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			return nil, fmt.Errorf("failed %s analysis: %v", outcome.Analyzer.Name, outcome.Err)
+		}
+		results[outcome.Analyzer] = outcome.Result
+	}
+	graph := results[callgraph.Analyzer].(*callgraph.Result)
+	modules := results[gomod.Analyzer].(*gomod.Result)
+	safePtrs := results[pointerescapes.Analyzer].(*pointerescapes.Result).SafePtrs
+	async := results[nogofunc.Analyzer].(*nogofunc.Result).AsyncSignatures
+
+	result := summary.New()
+	for _, decl := range graph.PtrSignatures {
+		if !exported(decl.Signature) {
+			continue
+		}
+		pkg, ok := importPath(modules.ModuleFor(decl.Pos), decl.Dir)
+		if !ok {
+			log.Printf("cannot determine the import path of the package in %s", decl.Dir)
+			continue
+		}
+		key := decl.Name
+		if decl.Recv != "" {
+			key = decl.Recv + "." + decl.Name
+		}
+		safe := append([]int(nil), safePtrs[decl.Signature]...)
+		sort.Ints(safe)
+		_, startsGoroutine := async[decl.Signature]
+		result.Add(pkg, key, summary.Func{
+			Arity:        decl.Arity,
+			SafePtrs:     safe,
+			NoGoroutines: !startsGoroutine,
+		})
+	}
+	return result, nil
+}
+
+// exported returns true if the provided signature can be called from other packages.
+func exported(sig callgraph.Signature) bool {
+	if sig.Dir == "" || sig.Package == "main" || strings.HasSuffix(sig.Package, "_test") {
+		return false
+	}
+	return ast.IsExported(sig.Name) && (sig.Recv == "" || ast.IsExported(sig.Recv))
+}
+
+// importPath returns the import path of the package found in the provided slash-separated directory of the
+// provided module.
+func importPath(mod *gomod.Module, dir string) (string, bool) {
+	if mod == nil || mod.Path == "" {
+		return "", false
+	}
+	if dir == mod.Dir {
+		return mod.Path, true
+	}
+	rel := strings.TrimPrefix(dir, mod.Dir+"/")
+	if rel == dir {
+		return "", false
+	}
+	return path.Join(mod.Path, rel), true
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/github-vet/bots/cmd/vet-bot/summary"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeDir(t *testing.T) {
+	result, err := summarizeDir("testdata/lib")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]map[string]summary.Func{
+		"example.com/lib": {
+			"Read":  {Arity: 1, SafePtrs: []int{0}, NoGoroutines: true},
+			"Save":  {Arity: 1, NoGoroutines: true},
+			"Spawn": {Arity: 1, SafePtrs: []int{0}},
+			"Both":  {Arity: 2, SafePtrs: []int{0}, NoGoroutines: true},
+		},
+		"example.com/lib/store": {
+			"Store.Put": {Arity: 1, NoGoroutines: true},
+			"Store.Len": {Arity: 1, SafePtrs: []int{0}, NoGoroutines: true},
+		},
+	}, result.Packages)
+}
+
+func TestEscapePath(t *testing.T) {
+	assert.Equal(t, "github.com/!burnt!sushi/toml", escapePath("github.com/BurntSushi/toml"))
+	assert.Equal(t, "v1.0.0", escapePath("v1.0.0"))
+}
@@ -0,0 +1,30 @@
+package lib
+
+var saved *int
+
+// Read only reads its argument.
+func Read(x *int) int {
+	return *x
+}
+
+// Save stores its argument.
+func Save(x *int) {
+	saved = x
+}
+
+// Spawn starts a goroutine.
+func Spawn(x *int) {
+	go func() {
+		println(*x)
+	}()
+}
+
+// Both reads its first argument and saves its second.
+func Both(x, y *int) {
+	Read(x)
+	Save(y)
+}
+
+func unexported(x *int) {
+	saved = x
+}
@@ -0,0 +1,16 @@
+package store
+
+// Store holds onto pointers.
+type Store struct {
+	ptrs []*int
+}
+
+// Put stores its argument.
+func (s *Store) Put(x *int) {
+	s.ptrs = append(s.ptrs, x)
+}
+
+// Len does not use its argument.
+func (s *Store) Len(x *int) int {
+	return len(s.ptrs)
+}
@@ -3,38 +3,141 @@ package acceptlist
 import (
 	"go/ast"
 	"io/ioutil"
+	"sync/atomic"
 
 	"github.com/github-vet/bots/cmd/vet-bot/packid"
+	"github.com/github-vet/bots/cmd/vet-bot/stats"
 	"gopkg.in/yaml.v2"
 )
 
-// GlobalAcceptList stores the list of well-known third-party functions which can be ignored by analyzers.
-// If the function is nil, no accept list is set.
-var GlobalAcceptList *AcceptList
+// globalAcceptList stores the list of well-known third-party functions which can be ignored by analyzers, as an
+// *AcceptList. It is only accessed atomically, since the accept list may be reloaded while analyzers are running.
+var globalAcceptList atomic.Value
+
+// GlobalAcceptList returns the current accept list. If the result is nil, no accept list is set.
+func GlobalAcceptList() *AcceptList {
+	acceptList, _ := globalAcceptList.Load().(*AcceptList)
+	return acceptList
+}
+
+// SetGlobalAcceptList replaces the current accept list with the provided one; nil unsets the accept list. Analyzers
+// which are running concurrently see either the previous list or the new list in full.
+func SetGlobalAcceptList(acceptList *AcceptList) {
+	globalAcceptList.Store(acceptList)
+}
 
 // AcceptList stores a list of functions from third-party packages which are known not to start
-// any Goroutines or store a reference to any of their pointer arguments.
+// any Goroutines or store a reference to any of their pointer arguments. Methods are listed by the name of their
+// receiver's type and the name of the method, separated by a dot, e.g. "Buffer.WriteString".
 type AcceptList struct {
 	Accept map[string]map[string]struct{}
+	// Entries stores a list of functions and methods from third-party packages which are only known to be safe for
+	// some of their arguments. Like Accept, it is keyed by package path, and then by the name of each function;
+	// methods are keyed by the name of their receiver type and the name of the method, separated by a dot.
+	Entries map[string]map[string]Entry
+	// Async stores a list of functions and methods from third-party packages which are known to run a function
+	// passed to them asynchronously, such as (*errgroup.Group).Go. Like Accept, it is keyed by package path, and
+	// methods are listed by the name of their receiver's type and the name of the method, e.g. "Group.Go".
+	Async map[string]map[string]struct{}
+}
+
+// Entry describes a function from a third-party package which is only known to be safe for some of its arguments.
+type Entry struct {
+	// Name is the name of the function or method.
+	Name string `yaml:"name"`
+	// Receiver is the name of the base type of the receiver of a method. It is empty for functions.
+	Receiver string `yaml:"receiver,omitempty"`
+	// SafeArgs lists the indices of the arguments which the function is known not to store.
+	SafeArgs []int `yaml:"safe_args,omitempty"`
+	// Goroutines is true if the function may start a goroutine, in which case none of its arguments are safe.
+	Goroutines bool `yaml:"goroutines,omitempty"`
+
+	all bool // true if the entry was listed only by name, in which case every argument is safe
+}
+
+// Key returns the key used to look up the entry in its package.
+func (e Entry) Key() string {
+	if e.Receiver == "" {
+		return e.Name
+	}
+	return e.Receiver + "." + e.Name
+}
+
+// SafeArg returns true if the function described by the entry does not start any goroutines, and is known not to
+// store the argument found at the provided index.
+func (e Entry) SafeArg(idx int) bool {
+	if e.Goroutines {
+		return false
+	}
+	if e.all {
+		return true
+	}
+	for _, safe := range e.SafeArgs {
+		if safe == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalYAML allows entries to be listed either by name alone, or as a map describing which arguments are safe.
+func (e *Entry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*e = Entry{Name: name, all: true}
+		return nil
+	}
+	type plain Entry // avoids recursing into UnmarshalYAML
+	var entry plain
+	if err := unmarshal(&entry); err != nil {
+		return err
+	}
+	*e = Entry(entry)
+	return nil
 }
 
-// UnmarshalAcceptList unmarshals an AcceptList from a yaml file.
+// UnmarshalAcceptList unmarshals an AcceptList from a yaml file. Each function listed under the accept key is
+// either the name of a function, all of whose arguments are safe, or an Entry describing which of its arguments are
+// safe.
 func UnmarshalAcceptList(data []byte) (AcceptList, error) {
 	var unmarshaled struct {
-		Accept map[string][]string
+		Accept map[string][]Entry
+		Async  map[string][]string
 	}
 	err := yaml.Unmarshal(data, &unmarshaled)
 	if err != nil {
 		return AcceptList{}, err
 	}
-	result := AcceptList{make(map[string]map[string]struct{})}
-	for key, strs := range unmarshaled.Accept {
-		result.Accept[key] = make(map[string]struct{})
+	accept := make(map[string][]string)
+	entries := make(map[string]map[string]Entry)
+	for pkg, list := range unmarshaled.Accept {
+		for _, entry := range list {
+			if entry.all {
+				accept[pkg] = append(accept[pkg], entry.Name)
+				continue
+			}
+			if _, ok := entries[pkg]; !ok {
+				entries[pkg] = make(map[string]Entry)
+			}
+			entries[pkg][entry.Key()] = entry
+		}
+	}
+	return AcceptList{
+		Accept:  toSets(accept),
+		Entries: entries,
+		Async:   toSets(unmarshaled.Async),
+	}, nil
+}
+
+func toSets(lists map[string][]string) map[string]map[string]struct{} {
+	result := make(map[string]map[string]struct{})
+	for key, strs := range lists {
+		result[key] = make(map[string]struct{})
 		for _, str := range strs {
-			result.Accept[key][str] = struct{}{}
+			result[key][str] = struct{}{}
 		}
 	}
-	return result, nil
+	return result
 }
 
 // AcceptListFromFile reads in the accept list from the provided file.
@@ -50,25 +153,77 @@ func AcceptListFromFile(path string) (AcceptList, error) {
 	return result, nil
 }
 
-// IgnoreCall returns true iff the provided callExpr matches the package of a whitelisted call.
+// IgnoreCall returns true iff the provided callExpr matches the package of a whitelisted call. Method calls are
+// matched against entries of the form Type.Method, whenever the type of their receiver can be found.
 func IgnoreCall(pr *packid.PackageResolver, callExpr *ast.CallExpr, stack []ast.Node) bool {
-	if GlobalAcceptList == nil || GlobalAcceptList.Accept == nil {
+	acceptList := GlobalAcceptList()
+	if acceptList == nil || acceptList.Accept == nil || callExpr == nil {
 		return false
 	}
-	fun, ok := callExpr.Fun.(*ast.SelectorExpr)
-	if !ok {
-		return false
-	}
-	pkg, err := pr.PackageFor(callExpr, stack)
+	pkg, name, err := pr.QualifiedCallee(callExpr, stack)
 	if err != nil {
 		return false
 	}
-	acceptFuncs, ok := GlobalAcceptList.Accept[pkg]
+	acceptFuncs, ok := acceptList.Accept[pkg]
 	if !ok {
 		return false
 	}
-	_, ok = acceptFuncs[fun.Sel.Name]
-	return ok
+	if _, ok = acceptFuncs[name]; !ok {
+		return false
+	}
+	if pr.AmbiguousCallee(callExpr, stack) {
+		stats.AddCount(stats.StatAcceptListAmbiguousCalls, 1)
+	}
+	return true
+}
+
+// SafeArg returns true iff the argument at the provided index of callExpr is known to be safe, either because the
+// function called is accepted outright, or because it is listed with an Entry which marks the argument safe.
+func SafeArg(pr *packid.PackageResolver, callExpr *ast.CallExpr, stack []ast.Node, idx int) bool {
+	if IgnoreCall(pr, callExpr, stack) {
+		return true
+	}
+	entry, ok := lookupEntry(pr, callExpr, stack)
+	return ok && entry.SafeArg(idx)
+}
+
+// StartsGoroutine returns true iff the function called by callExpr is listed with an Entry which says it may start a
+// goroutine.
+func StartsGoroutine(pr *packid.PackageResolver, callExpr *ast.CallExpr, stack []ast.Node) bool {
+	entry, ok := lookupEntry(pr, callExpr, stack)
+	return ok && entry.Goroutines
+}
+
+// lookupEntry finds the Entry describing the function called by callExpr, if any.
+func lookupEntry(pr *packid.PackageResolver, callExpr *ast.CallExpr, stack []ast.Node) (Entry, bool) {
+	acceptList := GlobalAcceptList()
+	if acceptList == nil || acceptList.Entries == nil || callExpr == nil {
+		return Entry{}, false
+	}
+	pkg, name, err := pr.QualifiedCallee(callExpr, stack)
+	if err != nil {
+		return Entry{}, false
+	}
+	entry, ok := acceptList.Entries[pkg][name]
+	return entry, ok
+}
+
+// AsyncCall returns the qualified name of the function called by the provided callExpr iff it matches a call which
+// is known to run its function arguments asynchronously. Method calls are matched against entries of the form
+// Type.Method, and only when the type of their receiver can be found.
+func AsyncCall(pr *packid.PackageResolver, callExpr *ast.CallExpr, stack []ast.Node) (string, bool) {
+	acceptList := GlobalAcceptList()
+	if acceptList == nil || acceptList.Async == nil || callExpr == nil {
+		return "", false
+	}
+	pkg, name, err := pr.QualifiedCallee(callExpr, stack)
+	if err != nil {
+		return "", false
+	}
+	if _, ok := acceptList.Async[pkg][name]; !ok {
+		return "", false
+	}
+	return pkg + "." + name, true
 }
 
 // LoadAcceptList loads the accept list from the provided file path. If any errors
@@ -76,7 +231,27 @@ func IgnoreCall(pr *packid.PackageResolver, callExpr *ast.CallExpr, stack []ast.
 func LoadAcceptList(path string) error {
 	acceptList, err := AcceptListFromFile(path)
 	if err == nil {
-		GlobalAcceptList = &acceptList
+		SetGlobalAcceptList(&acceptList)
 	}
 	return err
 }
+
+// Flag is a flag.Value which loads the global accept list from the path it is set to. It allows the accept list to
+// be configured by drivers which own the command-line, such as those found in the analysis/multichecker package.
+type Flag struct {
+	path string
+}
+
+// String returns the path of the accept list which was loaded.
+func (f *Flag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.path
+}
+
+// Set loads the accept list from the provided path.
+func (f *Flag) Set(path string) error {
+	f.path = path
+	return LoadAcceptList(path)
+}
@@ -0,0 +1,121 @@
+package acceptlist
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// Reloader reloads the global accept list from a file whenever the file is modified, or whenever a reload is requested
+// (e.g. on SIGHUP). Reloads only happen when MaybeReload is called, so callers can make sure the accept list does
+// not change while a repository is being vetted.
+type Reloader struct {
+	path      string
+	modTime   time.Time
+	requested chan struct{}
+}
+
+// NewReloader loads the global accept list from the provided path, and returns a Reloader which reloads it from the
+// same path.
+func NewReloader(path string) (*Reloader, error) {
+	r := &Reloader{path: path, requested: make(chan struct{}, 1)}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := LoadAcceptList(path); err != nil {
+		return nil, err
+	}
+	r.modTime = info.ModTime()
+	return r, nil
+}
+
+// Request asks for the accept list to be reloaded the next time MaybeReload is called, even if the file has not been
+// modified. It is safe to call from any goroutine.
+func (r *Reloader) Request() {
+	select {
+	case r.requested <- struct{}{}:
+	default: // a reload has already been requested
+	}
+}
+
+// MaybeReload reloads the accept list if a reload was requested or the file was modified since it was last loaded,
+// and logs each entry which was added or removed. The global accept list is only replaced once the new list has been
+// read in full; if it can't be read, the current list is kept, and an error is returned. It returns true only if the
+// accept list was reloaded.
+func (r *Reloader) MaybeReload() (bool, error) {
+	requested := false
+	select {
+	case <-r.requested:
+		requested = true
+	default:
+	}
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, err
+	}
+	if !requested && info.ModTime().Equal(r.modTime) {
+		return false, nil
+	}
+	acceptList, err := AcceptListFromFile(r.path)
+	if err != nil {
+		return false, err
+	}
+	r.modTime = info.ModTime()
+
+	added, removed := Diff(GlobalAcceptList(), &acceptList)
+	SetGlobalAcceptList(&acceptList)
+	log.Printf("reloaded accept list from %s; %d entries added, %d entries removed", r.path, len(added), len(removed))
+	for _, entry := range added {
+		log.Printf("accept list entry added: %s", entry)
+	}
+	for _, entry := range removed {
+		log.Printf("accept list entry removed: %s", entry)
+	}
+	return true, nil
+}
+
+// Diff describes each entry found in next but not in prev as added, and each entry found in prev but not in next as
+// removed. Either list may be nil.
+func Diff(prev, next *AcceptList) (added, removed []string) {
+	prevEntries, nextEntries := prev.describe(), next.describe()
+	for entry := range nextEntries {
+		if _, ok := prevEntries[entry]; !ok {
+			added = append(added, entry)
+		}
+	}
+	for entry := range prevEntries {
+		if _, ok := nextEntries[entry]; !ok {
+			removed = append(removed, entry)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// describe returns a human-readable description of every entry in the accept list.
+func (al *AcceptList) describe() map[string]struct{} {
+	result := make(map[string]struct{})
+	if al == nil {
+		return result
+	}
+	for pkg, names := range al.Accept {
+		for name := range names {
+			result[fmt.Sprintf("accept %s.%s", pkg, name)] = struct{}{}
+		}
+	}
+	for pkg, entries := range al.Entries {
+		for key, entry := range entries {
+			result[fmt.Sprintf("accept %s.%s (safe_args: %v, goroutines: %t)", pkg, key, entry.SafeArgs, entry.Goroutines)] = struct{}{}
+		}
+	}
+	for pkg, names := range al.Async {
+		for name := range names {
+			result[fmt.Sprintf("async %s.%s", pkg, name)] = struct{}{}
+		}
+	}
+	return result
+}
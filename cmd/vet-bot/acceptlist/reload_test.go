@@ -0,0 +1,83 @@
+package acceptlist
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloader(t *testing.T) {
+	t.Cleanup(func() { SetGlobalAcceptList(nil) })
+	dir, err := ioutil.TempDir("", "acceptlist")
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "accept.yml")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("accept:\n  fmt:\n    - Println\n"), 0644))
+
+	r, err := NewReloader(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, GlobalAcceptList().Accept["fmt"], "Println")
+
+	reloaded, err := r.MaybeReload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded, "unmodified files should not be reloaded")
+
+	// modifying the file triggers a reload.
+	assert.NoError(t, ioutil.WriteFile(path, []byte("accept:\n  fmt:\n    - Printf\n"), 0644))
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(path, later, later))
+	reloaded, err = r.MaybeReload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Contains(t, GlobalAcceptList().Accept["fmt"], "Printf")
+	assert.NotContains(t, GlobalAcceptList().Accept["fmt"], "Println")
+
+	// invalid files are not loaded, and the current accept list is kept.
+	assert.NoError(t, ioutil.WriteFile(path, []byte("accept: [\n"), 0644))
+	r.Request()
+	reloaded, err = r.MaybeReload()
+	assert.Error(t, err)
+	assert.False(t, reloaded)
+	assert.Contains(t, GlobalAcceptList().Accept["fmt"], "Printf")
+
+	// requested reloads happen even if the file was not modified.
+	assert.NoError(t, ioutil.WriteFile(path, []byte("accept:\n  fmt:\n    - Print\n"), 0644))
+	assert.NoError(t, os.Chtimes(path, later, later))
+	reloaded, err = r.MaybeReload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+	r.Request()
+	reloaded, err = r.MaybeReload()
+	assert.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Contains(t, GlobalAcceptList().Accept["fmt"], "Print")
+}
+
+func TestDiff(t *testing.T) {
+	prev, err := UnmarshalAcceptList([]byte("accept:\n  fmt:\n    - Println\n    - Printf\n  encoding/json:\n    - name: Unmarshal\n      safe_args: [1]\n"))
+	assert.NoError(t, err)
+	next, err := UnmarshalAcceptList([]byte("accept:\n  fmt:\n    - Println\n  encoding/json:\n    - name: Unmarshal\n      safe_args: [0, 1]\nasync:\n  testing:\n    - T.Run\n"))
+	assert.NoError(t, err)
+
+	added, removed := Diff(&prev, &next)
+	assert.Equal(t, []string{
+		"accept encoding/json.Unmarshal (safe_args: [0 1], goroutines: false)",
+		"async testing.T.Run",
+	}, added)
+	assert.Equal(t, []string{
+		"accept encoding/json.Unmarshal (safe_args: [1], goroutines: false)",
+		"accept fmt.Printf",
+	}, removed)
+
+	added, removed = Diff(nil, &prev)
+	assert.Len(t, added, 3)
+	assert.Empty(t, removed)
+}
@@ -1,10 +1,16 @@
 package acceptlist
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"testing"
 
+	"github.com/github-vet/bots/cmd/vet-bot/driver"
 	"github.com/github-vet/bots/cmd/vet-bot/packid"
+	"github.com/github-vet/bots/cmd/vet-bot/stats"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
 )
 
 func TestAcceptListFromFile(t *testing.T) {
@@ -17,10 +23,68 @@ func TestAcceptListFromFile(t *testing.T) {
 	assert.Contains(t, list.Accept["fmt"], "Printf")
 	assert.Contains(t, list.Accept, "yaml")
 	assert.Contains(t, list.Accept["yaml"], "Unmarshal")
+	assert.NotContains(t, list.Accept, "encoding/json")
+	assert.Equal(t, []int{0, 1}, list.Entries["encoding/json"]["Unmarshal"].SafeArgs)
+	assert.Contains(t, list.Entries["example.com/cache"], "Cache.Get")
+	assert.True(t, list.Entries["example.com/cache"]["Spawn"].Goroutines)
+	assert.Contains(t, list.Async, "golang.org/x/sync/errgroup")
+	assert.Contains(t, list.Async["golang.org/x/sync/errgroup"], "Group.Go")
 }
 
 func TestZeroValue(t *testing.T) { // not technically whitebox
 	assert.NotPanics(t, func() {
 		IgnoreCall(&packid.PackageResolver{}, nil, nil)
+		AsyncCall(&packid.PackageResolver{}, nil, nil)
+		SafeArg(&packid.PackageResolver{}, nil, nil, 0)
+		StartsGoroutine(&packid.PackageResolver{}, nil, nil)
 	})
 }
+
+func TestFlag(t *testing.T) {
+	defer func() { SetGlobalAcceptList(nil) }()
+	var f Flag
+	assert.NoError(t, f.Set("testdata/acceptlist.yaml"))
+	assert.Equal(t, "testdata/acceptlist.yaml", f.String())
+	if assert.NotNil(t, GlobalAcceptList()) {
+		assert.Contains(t, GlobalAcceptList().Accept, "fmt")
+	}
+	assert.Error(t, f.Set("testdata/missing.yaml"))
+}
+
+func TestEntrySafeArg(t *testing.T) {
+	list, err := UnmarshalAcceptList([]byte("accept:\n  pkg:\n    - All\n    - name: Some\n      safe_args: [1]\n    - name: Async\n      safe_args: [0]\n      goroutines: true\n"))
+	assert.NoError(t, err)
+	assert.Contains(t, list.Accept["pkg"], "All")
+	some := list.Entries["pkg"]["Some"]
+	assert.False(t, some.SafeArg(0))
+	assert.True(t, some.SafeArg(1))
+	assert.False(t, list.Entries["pkg"]["Async"].SafeArg(0))
+	assert.Equal(t, "Cache.Put", Entry{Name: "Put", Receiver: "Cache"}.Key())
+}
+
+func TestAmbiguousCalls(t *testing.T) {
+	defer func() { SetGlobalAcceptList(nil) }()
+	stats.Clear()
+	list, err := UnmarshalAcceptList([]byte("accept:\n  gopkg.in/yaml.v2:\n    - Unmarshal\n  fmt:\n    - Println\n"))
+	assert.NoError(t, err)
+	SetGlobalAcceptList(&list)
+
+	src := "package p\n\nimport (\n\t\"fmt\"\n\t\"gopkg.in/yaml.v2\"\n)\n\nfunc f() {\n\tyaml.Unmarshal(nil, nil)\n\tfmt.Println()\n}\n"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	assert.NoError(t, err)
+	outcomes, err := driver.Run(fset, []*ast.File{file}, nil, []*analysis.Analyzer{packid.Analyzer}, func(analysis.Diagnostic) {})
+	assert.NoError(t, err)
+	pr := outcomes[len(outcomes)-1].Result.(*packid.PackageResolver)
+
+	var calls []*ast.CallExpr
+	ast.Inspect(file, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			calls = append(calls, call)
+		}
+		return true
+	})
+	assert.True(t, IgnoreCall(pr, calls[0], []ast.Node{file}))
+	assert.True(t, IgnoreCall(pr, calls[1], []ast.Node{file}))
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatAcceptListAmbiguousCalls))
+}
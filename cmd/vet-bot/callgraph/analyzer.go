@@ -3,30 +3,52 @@ package callgraph
 import (
 	"go/ast"
 	"go/token"
+	"go/types"
 	"log"
+	"path"
+	"path/filepath"
 	"reflect"
+	"strings"
 
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
+	"github.com/github-vet/bots/cmd/vet-bot/packid"
 	"github.com/github-vet/bots/cmd/vet-bot/stats"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
 )
 
 // Analyzer provides an approximate callgraph based on function name and arity. Edges in the callgraph
-// are only present if both functions are declared in the source and both take pointer arguments.
+// are only present if both functions are declared in the source and both take pointer or function arguments.
+//
+// Methods are further distinguished by the name of their receiver's base type, and functions and methods declared
+// in the source are distinguished by their package, which is identified by its directory and package clause. Calls
+// into other packages found in the same module are resolved using the module path from go.mod. Calls are resolved
+// to a method only when the type of the receiver can be found from the declaration of the variable it names;
+// otherwise, they fall back to name and arity, and reach every declaration sharing that name and arity via an edge
+// in the callgraph.
+//
+// If type information is available, functions are identified by the full name of their types.Func instead, and
+// static calls are resolved to the function they call. Calls which cannot be resolved statically, such as calls to
+// interface methods or function values, fall back to name and arity. Each declared function is given an edge from
+// the node for its name and arity, so these calls still reach every function they may call. Arity is the number of
+// parameters declared by the function called, rather than the number of arguments passed, so that calls to variadic
+// functions share the signature of their declaration.
 var Analyzer = &analysis.Analyzer{
 	Name:             "callgraph",
 	Doc:              "computes an approximate callgraph based on function arity, name, and nothing else",
 	Run:              run,
 	RunDespiteErrors: true,
-	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	Requires:         []*analysis.Analyzer{inspect.Analyzer, gomod.Analyzer, packid.Analyzer},
 	ResultType:       reflect.TypeOf((*Result)(nil)),
 }
 
 // Result is the result of the callgraph analyzer.
 type Result struct {
 	// PtrSignatures contains a record for each declared function signature found to contain a pointer
-	// during analysis.
+	// or a function during analysis. Function arguments are tracked since they may capture references to
+	// variables, just as pointers do.
 	PtrSignatures []DeclaredSignature
 	// PtrCalls contains a record for each function call to a function with a pointer signature found
 	// during analysis.
@@ -35,6 +57,24 @@ type Result struct {
 	// in the callgraph between each pair of functions whose declarations are found in the source material
 	// and which accept a pointer variable in their signature.
 	ApproxCallGraph *CallGraph
+	// Aliases maps a coarser signature, such as one consisting only of name and arity, to the signatures of every
+	// declaration in PtrSignatures it may refer to.
+	Aliases map[Signature][]Signature
+
+	fset        *token.FileSet
+	info        *types.Info
+	modules     *gomod.Result
+	imports     *packid.PackageResolver
+	files       map[*token.File]*ast.File
+	packages    map[string]string                  // the name of the package in each directory, ignoring external test packages
+	typeDecls   map[packageKey]map[string]ast.Expr // the type expression of each type declared by each package
+	declSigs    map[Signature]struct{}             // every signature which can be used to call any declaration
+	ptrDeclSigs map[Signature]struct{}             // every signature which can be used to call a declaration in PtrSignatures
+}
+
+// packageKey identifies a package found in the source by its directory and package clause.
+type packageKey struct {
+	dir, name string
 }
 
 // Call captures the signature of function calls along with the signature of the function from
@@ -50,10 +90,33 @@ type Call struct {
 }
 
 // Signature is only an approximation of the information needed to make a function call. It captures
-// only name and arity.
+// the name and arity of a function, along with the base type of its receiver and the directory of its package
+// whenever they are known.
 type Signature struct {
 	Name  string
 	Arity int
+	// Recv is the name of the base type of the receiver of a method; it is empty for functions, and for method calls
+	// whose receiver could not be resolved.
+	Recv string
+	// Dir is the slash-separated directory of the package declaring the function; it is empty whenever it is not
+	// known.
+	Dir string
+	// Package is the name found in the package clause of the package declaring the function, which distinguishes
+	// external test packages from the package they test. It is only set along with Dir.
+	Package string
+}
+
+// coarsenings returns every coarser signature which may be used to refer to the provided declaration, given the
+// signature made only from its name and arity.
+func coarsenings(decl, coarse Signature) []Signature {
+	var result []Signature
+	if decl.Recv != "" && decl.Dir != "" {
+		result = append(result, Signature{Name: decl.Name, Arity: decl.Arity, Recv: decl.Recv})
+	}
+	if coarse != decl {
+		result = append(result, coarse)
+	}
+	return result
 }
 
 // DeclaredSignature is a signature along with the position of its declaration.
@@ -65,8 +128,31 @@ type DeclaredSignature struct {
 func run(pass *analysis.Pass) (interface{}, error) {
 	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
 
-	result := Result{}
-	ptrDeclSigs := make(map[Signature]struct{}) // set of signatures with a declaration containing a pointer
+	result := Result{
+		Aliases:     make(map[Signature][]Signature),
+		fset:        pass.Fset,
+		info:        pass.TypesInfo,
+		modules:     pass.ResultOf[gomod.Analyzer].(*gomod.Result),
+		imports:     pass.ResultOf[packid.Analyzer].(*packid.PackageResolver),
+		files:       make(map[*token.File]*ast.File),
+		packages:    make(map[string]string),
+		typeDecls:   make(map[packageKey]map[string]ast.Expr),
+		declSigs:    make(map[Signature]struct{}),
+		ptrDeclSigs: make(map[Signature]struct{}), // set of signatures with a declaration containing a pointer
+	}
+
+	// group files by directory and package clause.
+	for _, file := range pass.Files {
+		tokFile := pass.Fset.File(file.Pos())
+		if tokFile == nil {
+			continue
+		}
+		result.files[tokFile] = file
+		if !strings.HasSuffix(file.Name.Name, "_test") {
+			result.packages[dirOf(tokFile)] = file.Name.Name
+		}
+		result.addTypeDecls(packageKey{dirOf(tokFile), file.Name.Name}, file)
+	}
 
 	// first pass grabs all declared functions which include pointers in their signatures.
 	declFilter := []ast.Node{
@@ -81,10 +167,19 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		if !ok {
 			log.Fatalf("node filter %v was a lie", declFilter)
 		}
-		parsedSig := parseFuncDecl(decl)
-		if funcDeclTakesPointers(decl) {
-			ptrDeclSigs[parsedSig.Signature] = struct{}{}
+		parsedSig := result.parseFuncDecl(decl)
+		coarse := coarsenings(parsedSig.Signature, parseFuncDecl(decl).Signature)
+		result.declSigs[parsedSig.Signature] = struct{}{}
+		for _, sig := range coarse {
+			result.declSigs[sig] = struct{}{}
+		}
+		if funcDeclTakesPointers(decl) || result.funcDeclTakesFuncs(decl) {
+			result.ptrDeclSigs[parsedSig.Signature] = struct{}{}
 			result.PtrSignatures = append(result.PtrSignatures, parsedSig)
+			for _, sig := range coarse {
+				result.ptrDeclSigs[sig] = struct{}{}
+				result.Aliases[sig] = append(result.Aliases[sig], parsedSig.Signature)
+			}
 		}
 		return true
 	})
@@ -103,8 +198,8 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		if !ok {
 			log.Fatalf("node filter %v was a lie", callFilter)
 		}
-		if _, ok := ptrDeclSigs[SignatureFromCallExpr(callExpr)]; ok {
-			parsedCall := parseCallExpr(callExpr, stack)
+		if _, ok := result.ptrDeclSigs[result.CallSignature(callExpr)]; ok {
+			parsedCall := result.parseCallExpr(callExpr, stack)
 			result.PtrCalls = append(result.PtrCalls, parsedCall)
 		}
 		return true
@@ -116,19 +211,88 @@ func run(pass *analysis.Pass) (interface{}, error) {
 	return &result, nil
 }
 
-// parseFuncDecl retrieves a DeclaredSignature from a FuncDecl
+// CallSignature retrieves the signature of a call expression. If type information is available and the call can be
+// resolved statically, the full name of the function called is used as the name of the signature. Otherwise, if no
+// function is declared with the receiver and package found for the call, the signature falls back to a coarser one
+// which only includes the name of the receiver, or only the name and arity of the function called.
+func (r *Result) CallSignature(call *ast.CallExpr) Signature {
+	if r.info != nil {
+		if fn := typeutil.StaticCallee(r.info, call); fn != nil {
+			return Signature{Name: fn.FullName(), Arity: declaredArity(fn)}
+		}
+	}
+	result := r.signatureFromCallExpr(call)
+	if _, ok := r.declSigs[result]; ok {
+		return result
+	}
+	result.Dir, result.Package = "", ""
+	if _, ok := r.declSigs[result]; ok {
+		return result
+	}
+	result.Recv = ""
+	return result
+}
+
+// DeclSignature retrieves the signature of a function declaration. If type information is available, the full name
+// of the function declared is used as the name of the signature.
+func (r *Result) DeclSignature(fdec *ast.FuncDecl) Signature {
+	return r.parseFuncDecl(fdec).Signature
+}
+
+// Declares returns true if the provided signature can be used to call any declaration in PtrSignatures.
+func (r *Result) Declares(sig Signature) bool {
+	_, ok := r.ptrDeclSigs[sig]
+	return ok
+}
+
+func (r *Result) parseFuncDecl(fdec *ast.FuncDecl) DeclaredSignature {
+	result := parseFuncDecl(fdec)
+	if r.info != nil {
+		if fn, ok := r.info.Defs[fdec.Name].(*types.Func); ok {
+			result.Name, result.Arity = fn.FullName(), declaredArity(fn)
+			return result
+		}
+	}
+	result.Signature = r.signatureFromFuncDecl(fdec)
+	return result
+}
+
+// parseFuncDecl retrieves a DeclaredSignature consisting only of name and arity from a FuncDecl
 func parseFuncDecl(fdec *ast.FuncDecl) DeclaredSignature {
 	result := DeclaredSignature{Pos: fdec.Pos()}
 	result.Name = fdec.Name.Name
 	if fdec.Type.Params != nil {
 		for _, param := range fdec.Type.Params.List {
+			if len(param.Names) == 0 {
+				result.Arity++ // unnamed parameters
+			}
 			result.Arity += len(param.Names)
 		}
-
 	}
 	return result
 }
 
+// declaredArity returns the number of parameters declared by the provided function, counting a variadic parameter
+// once, so that calls passing any number of variadic arguments share the signature of the declaration.
+func declaredArity(fn *types.Func) int {
+	return fn.Type().(*types.Signature).Params().Len()
+}
+
+// callArity returns the arity of the function called by the provided call. If type information is available, the
+// number of parameters declared by the type of the function called is used, so that calls to variadic functions
+// through a function value or an interface still share the arity of the declarations they may call. Otherwise, the
+// number of arguments is used.
+func (r *Result) callArity(call *ast.CallExpr) int {
+	if r.info != nil {
+		if typ := r.info.TypeOf(call.Fun); typ != nil {
+			if sig, ok := typ.Underlying().(*types.Signature); ok {
+				return sig.Params().Len()
+			}
+		}
+	}
+	return len(call.Args)
+}
+
 func funcDeclTakesPointers(fdec *ast.FuncDecl) bool {
 	if fdec.Type.Params == nil {
 		return false
@@ -142,23 +306,93 @@ func funcDeclTakesPointers(fdec *ast.FuncDecl) bool {
 	return result
 }
 
+// funcDeclTakesFuncs returns true if any parameter of the provided function declaration is itself a function.
+func (r *Result) funcDeclTakesFuncs(fdec *ast.FuncDecl) bool {
+	if fdec.Type.Params == nil {
+		return false
+	}
+	for _, param := range fdec.Type.Params.List {
+		if r.IsFuncType(param.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFuncType returns true if the provided type expression denotes a function type. Named types are resolved using
+// type information if it is available. Otherwise, they are resolved by following the type declarations found in the
+// source, including those of other packages in the same module.
+func (r *Result) IsFuncType(expr ast.Expr) bool {
+	if r.info != nil {
+		if typ := r.info.TypeOf(expr); typ != nil {
+			_, ok := typ.Underlying().(*types.Signature)
+			return ok
+		}
+	}
+	return r.isFuncType(expr, make(map[ast.Expr]struct{}))
+}
+
+func (r *Result) isFuncType(expr ast.Expr, seen map[ast.Expr]struct{}) bool {
+	if _, ok := seen[expr]; ok {
+		return false // the type is declared in terms of itself
+	}
+	seen[expr] = struct{}{}
+	switch typed := expr.(type) {
+	case *ast.FuncType:
+		return true
+	case *ast.ParenExpr:
+		return r.isFuncType(typed.X, seen)
+	case *ast.Ident:
+		if typed.Obj != nil {
+			spec, ok := typed.Obj.Decl.(*ast.TypeSpec)
+			return ok && r.isFuncType(spec.Type, seen)
+		}
+		// types declared in another file of the same package are not resolved by the parser.
+		dir, name := r.localPackage(typed.Pos())
+		decl, ok := r.typeDecls[packageKey{dir, name}][typed.Name]
+		return ok && r.isFuncType(decl, seen)
+	case *ast.SelectorExpr:
+		pkg, ok := typed.X.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		dir, name := r.importedPackage(pkg.Name, typed.Pos())
+		decl, ok := r.typeDecls[packageKey{dir, name}][typed.Sel.Name]
+		return ok && r.isFuncType(decl, seen)
+	}
+	return false
+}
+
+// addTypeDecls records the type expression of every type declared at the top level of the provided file.
+func (r *Result) addTypeDecls(pkg packageKey, file *ast.File) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			spec, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				continue
+			}
+			if _, ok := r.typeDecls[pkg]; !ok {
+				r.typeDecls[pkg] = make(map[string]ast.Expr)
+			}
+			r.typeDecls[pkg][spec.Name.Name] = spec.Type
+		}
+	}
+}
+
 // parseCallExpr retrieves relevant information about a function call, including its signature
 // and the signature of the function declaration in which it appears.
-func parseCallExpr(call *ast.CallExpr, stack []ast.Node) Call {
+func (r *Result) parseCallExpr(call *ast.CallExpr, stack []ast.Node) Call {
 	result := Call{
-		Signature: SignatureFromCallExpr(call),
+		Signature: r.CallSignature(call),
 		Pos:       call.Pos(),
 	}
 	outerFunc := outermostFuncDecl(stack)
 	if outerFunc != nil {
-		result.Caller = parseFuncDecl(outerFunc)
-	}
-	result.Arity = len(call.Args)
-	switch typed := call.Fun.(type) {
-	case *ast.Ident:
-		result.Name = typed.Name
-	case *ast.SelectorExpr:
-		result.Name = typed.Sel.Name
+		result.Caller = r.parseFuncDecl(outerFunc)
 	}
 	// obtain the source positions of argument decalarations
 	for _, arg := range call.Args {
@@ -186,21 +420,159 @@ func outermostFuncDecl(stack []ast.Node) *ast.FuncDecl {
 	return nil
 }
 
-// SignatureFromCallExpr retrieves the signature of a call expression.
-func SignatureFromCallExpr(call *ast.CallExpr) Signature {
+// signatureFromCallExpr retrieves the signature of a call expression. Calls to functions in the same package, or in
+// another package of the same module, include the package. Method calls on variables include the base type of the
+// receiver whenever it can be found from the declaration of the variable, along with its package if the type is
+// declared in the module.
+func (r *Result) signatureFromCallExpr(call *ast.CallExpr) Signature {
 	result := Signature{
-		Arity: len(call.Args),
+		Arity: r.callArity(call),
 	}
 	switch typed := call.Fun.(type) {
 	case *ast.Ident:
 		result.Name = typed.Name
+		if typed.Obj == nil || typed.Obj.Kind == ast.Fun { // function values are never resolved
+			result.Dir, result.Package = r.localPackage(call.Pos())
+		}
 	case *ast.SelectorExpr:
 		result.Name = typed.Sel.Name
+		x, ok := typed.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		if x.Obj == nil { // identifiers which refer to packages are never resolved by the parser
+			result.Dir, result.Package = r.importedPackage(x.Name, call.Pos())
+			break
+		}
+		if x.Obj.Kind != ast.Var {
+			break
+		}
+		recv, qualifier := baseTypeName(declaredType(x))
+		if recv == "" {
+			break
+		}
+		result.Recv = recv
+		if qualifier == "" {
+			result.Dir, result.Package = r.localPackage(call.Pos())
+		} else {
+			result.Dir, result.Package = r.importedPackage(qualifier, call.Pos())
+		}
 	}
 	return result
 }
 
-// SignatureFromFuncDecl retrieves a signature from the provided FuncDecl.
-func SignatureFromFuncDecl(fdec *ast.FuncDecl) Signature {
-	return parseFuncDecl(fdec).Signature
+// signatureFromFuncDecl retrieves a signature from the provided FuncDecl.
+func (r *Result) signatureFromFuncDecl(fdec *ast.FuncDecl) Signature {
+	result := parseFuncDecl(fdec).Signature
+	if fdec.Recv != nil && len(fdec.Recv.List) > 0 {
+		result.Recv, _ = baseTypeName(fdec.Recv.List[0].Type)
+	}
+	result.Dir, result.Package = r.localPackage(fdec.Pos())
+	return result
+}
+
+// localPackage returns the directory and package name of the file containing the provided position.
+func (r *Result) localPackage(pos token.Pos) (dir, name string) {
+	tokFile := r.fset.File(pos)
+	file, ok := r.files[tokFile]
+	if !ok {
+		return "", ""
+	}
+	return dirOf(tokFile), file.Name.Name
+}
+
+// importedPackage returns the directory and package name of the package imported under the provided name by the
+// file containing the provided position, if it belongs to the same module as the file. Otherwise, the package is
+// treated as third-party, and both are empty.
+func (r *Result) importedPackage(name string, pos token.Pos) (dir, pkgName string) {
+	file, ok := r.files[r.fset.File(pos)]
+	if !ok {
+		return "", ""
+	}
+	importPath, err := r.imports.ImportPath(name, []ast.Node{file})
+	if err != nil {
+		return "", ""
+	}
+	dir, ok = r.modules.ModuleFor(pos).DirFor(importPath)
+	if !ok {
+		return "", ""
+	}
+	pkgName, ok = r.packages[dir]
+	if !ok {
+		return "", ""
+	}
+	return dir, pkgName
+}
+
+// dirOf returns the slash-separated directory of the provided file.
+func dirOf(file *token.File) string {
+	return path.Dir(filepath.ToSlash(file.Name()))
+}
+
+// declaredType returns the type expression found in the declaration of the variable named by the provided
+// identifier, if any. Variables initialized with a composite literal or a call to new are also resolved.
+func declaredType(x *ast.Ident) ast.Expr {
+	switch decl := x.Obj.Decl.(type) {
+	case *ast.Field:
+		return decl.Type
+	case *ast.ValueSpec:
+		if decl.Type != nil {
+			return decl.Type
+		}
+		if len(decl.Values) != len(decl.Names) {
+			return nil
+		}
+		for i, name := range decl.Names {
+			if name.Name == x.Name {
+				return valueType(decl.Values[i])
+			}
+		}
+	case *ast.AssignStmt:
+		if len(decl.Rhs) != len(decl.Lhs) {
+			return nil
+		}
+		for i, lhs := range decl.Lhs {
+			if id, ok := lhs.(*ast.Ident); ok && id.Name == x.Name {
+				return valueType(decl.Rhs[i])
+			}
+		}
+	}
+	return nil
+}
+
+// valueType returns the type expression of values whose type can be found syntactically.
+func valueType(expr ast.Expr) ast.Expr {
+	switch typed := expr.(type) {
+	case *ast.ParenExpr:
+		return valueType(typed.X)
+	case *ast.UnaryExpr:
+		if typed.Op == token.AND {
+			return valueType(typed.X)
+		}
+	case *ast.CompositeLit:
+		return typed.Type
+	case *ast.CallExpr:
+		if id, ok := typed.Fun.(*ast.Ident); ok && id.Name == "new" && len(typed.Args) == 1 {
+			return typed.Args[0]
+		}
+	}
+	return nil
+}
+
+// baseTypeName returns the name of the named type found in the provided type expression, after removing any
+// pointers. If the type is declared in another package, the name of the package is returned as its qualifier.
+func baseTypeName(expr ast.Expr) (name, qualifier string) {
+	switch typed := expr.(type) {
+	case *ast.ParenExpr:
+		return baseTypeName(typed.X)
+	case *ast.StarExpr:
+		return baseTypeName(typed.X)
+	case *ast.Ident:
+		return typed.Name, ""
+	case *ast.SelectorExpr:
+		if pkg, ok := typed.X.(*ast.Ident); ok {
+			return typed.Sel.Name, pkg.Name
+		}
+	}
+	return "", ""
 }
@@ -30,6 +30,12 @@ func resultToCallGraph(r Result) CallGraph {
 		callID := result.AddSignature(call.Signature)
 		result.AddCall(callerID, callID)
 	}
+	for coarse, aliases := range r.Aliases {
+		coarseID := result.AddSignature(coarse)
+		for _, alias := range aliases {
+			result.AddCall(coarseID, result.AddSignature(alias))
+		}
+	}
 	return result
 }
 
@@ -2,10 +2,23 @@ package callgraph_test
 
 import (
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
 	"strings"
 	"testing"
 
 	"github.com/github-vet/bots/cmd/vet-bot/callgraph"
+	"github.com/github-vet/bots/cmd/vet-bot/driver"
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
+	"github.com/github-vet/bots/cmd/vet-bot/packid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
 )
 
 func TestBFSWithStack(t *testing.T) {
@@ -41,7 +54,7 @@ func TestBFSWithStack(t *testing.T) {
 			}
 		}
 		for source := range test.adjacencyList {
-			graph.AddSignature(callgraph.Signature{"", source})
+			graph.AddSignature(callgraph.Signature{Arity: source})
 		}
 		printPath := func(stack []callgraph.Signature) string {
 			var sb strings.Builder
@@ -54,7 +67,7 @@ func TestBFSWithStack(t *testing.T) {
 			return sb.String()
 		}
 		var paths []string
-		source := callgraph.Signature{"", test.source}
+		source := callgraph.Signature{Arity: test.source}
 		graph.BFSWithStack(source, func(signature callgraph.Signature, stack []callgraph.Signature) {
 			if signature.Arity == test.sink {
 				paths = append(paths, printPath(stack))
@@ -73,3 +86,185 @@ func TestBFSWithStack(t *testing.T) {
 		}
 	}
 }
+
+func TestMethodSignatures(t *testing.T) {
+	src := `package p
+
+type A struct{}
+type B struct{}
+
+func (a *A) Add(x *int) {}
+func (b *B) Add(x *int) {}
+
+func get() interface{ Add(*int) } { return &A{} }
+
+func use(x *int) {
+	a := &A{}
+	a.Add(x)
+	var b B
+	b.Add(x)
+	unknown := get()
+	unknown.Add(x)
+}
+`
+	result := runAnalyzer(t, map[string]string{"pkg/p.go": src})
+
+	var calls []callgraph.Signature
+	for _, call := range result.PtrCalls {
+		calls = append(calls, call.Signature)
+	}
+	methodA := callgraph.Signature{Name: "Add", Arity: 1, Recv: "A", Dir: "pkg", Package: "p"}
+	methodB := callgraph.Signature{Name: "Add", Arity: 1, Recv: "B", Dir: "pkg", Package: "p"}
+	coarse := callgraph.Signature{Name: "Add", Arity: 1}
+	assert.Equal(t, []callgraph.Signature{methodA, methodB, coarse}, calls)
+	assert.ElementsMatch(t, []callgraph.Signature{methodA, methodB}, result.Aliases[coarse])
+
+	// calls which can only be resolved by name and arity reach both methods.
+	var reached []callgraph.Signature
+	result.ApproxCallGraph.BFSWithStack(coarse, func(sig callgraph.Signature, stack []callgraph.Signature) {
+		reached = append(reached, sig)
+	})
+	assert.ElementsMatch(t, []callgraph.Signature{coarse, methodA, methodB}, reached)
+}
+
+func TestPackages(t *testing.T) {
+	result := runAnalyzerInModules(t, map[string][]byte{"repo/go.mod": []byte("module example.com/repo\n")}, map[string]string{
+		"repo/a/a.go":      "package a\n\nfunc Parse(x *int) {}\n",
+		"repo/a/a_test.go": "package a_test\n\nfunc Parse(x *int) {}\n",
+		"repo/b/b.go":      "package b\n\nfunc Parse(x *int) {}\n",
+		"repo/main.go": `package main
+
+import (
+	"example.com/repo/b"
+	other "github.com/other/a"
+)
+
+func use(x *int) {
+	b.Parse(x)
+	other.Parse(x)
+}
+`,
+	})
+	var calls []callgraph.Signature
+	for _, call := range result.PtrCalls {
+		calls = append(calls, call.Signature)
+	}
+	assert.Equal(t, []callgraph.Signature{
+		{Name: "Parse", Arity: 1, Dir: "repo/b", Package: "b"},
+		{Name: "Parse", Arity: 1}, // calls into third-party packages are resolved only by name and arity.
+	}, calls)
+	assert.ElementsMatch(t, []callgraph.Signature{
+		{Name: "Parse", Arity: 1, Dir: "repo/a", Package: "a"},
+		{Name: "Parse", Arity: 1, Dir: "repo/a", Package: "a_test"},
+		{Name: "Parse", Arity: 1, Dir: "repo/b", Package: "b"},
+	}, result.Aliases[callgraph.Signature{Name: "Parse", Arity: 1}])
+}
+
+func TestNamedFuncTypes(t *testing.T) {
+	result := runAnalyzerInModules(t, map[string][]byte{"repo/go.mod": []byte("module example.com/repo\n")}, map[string]string{
+		"repo/task/task.go": "package task\n\ntype Func func()\n",
+		"repo/types.go":     "package main\n\ntype Handler Task\n",
+		"repo/main.go": `package main
+
+import "example.com/repo/task"
+
+type Task func()
+
+type Count int
+
+func runTask(t Task)          {}
+func runHandler(h Handler)    {}
+func runImported(f task.Func) {}
+func runCount(c Count)        {}
+`,
+	})
+	var names []string
+	for _, sig := range result.PtrSignatures {
+		names = append(names, sig.Name)
+	}
+	assert.ElementsMatch(t, []string{"runTask", "runHandler", "runImported"}, names)
+}
+
+func TestTypedSignatures(t *testing.T) {
+	src := `package p
+
+type Adder interface{ Add(x *int) }
+
+type A struct{}
+
+func (a *A) Add(x *int) {}
+
+func sum(total *int, xs ...int) {}
+
+func use(x, y *int, adder Adder) {
+	sum(x, 1, 2, 3)
+	adder.Add(y)
+}
+`
+	result := runTypedAnalyzer(t, src)
+	var calls []callgraph.Signature
+	for _, call := range result.PtrCalls {
+		calls = append(calls, call.Signature)
+		assert.True(t, result.Declares(call.Signature), "%v should match a declaration", call.Signature)
+	}
+	// variadic calls share the arity of their declaration, and interface method calls fall back to name and arity.
+	coarse := callgraph.Signature{Name: "Add", Arity: 1}
+	assert.Equal(t, []callgraph.Signature{{Name: "p.sum", Arity: 2}, coarse}, calls)
+	assert.Equal(t, []callgraph.Signature{{Name: "(*p.A).Add", Arity: 1}}, result.Aliases[coarse])
+}
+
+// runAnalyzer runs the callgraph analyzer, along with the analyzers it requires, over the provided source files.
+func runAnalyzer(t *testing.T, srcs map[string]string) *callgraph.Result {
+	return runAnalyzerInModules(t, nil, srcs)
+}
+
+// runAnalyzerInModules runs the callgraph analyzer over the provided source files, which belong to the modules
+// declared by the provided go.mod files.
+func runAnalyzerInModules(t *testing.T, goMods map[string][]byte, srcs map[string]string) *callgraph.Result {
+	fset := token.NewFileSet()
+	var names []string
+	for name := range srcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var files []*ast.File
+	for _, name := range names {
+		file, err := parser.ParseFile(fset, name, srcs[name], 0)
+		assert.NoError(t, err)
+		files = append(files, file)
+	}
+	pass := &analysis.Pass{
+		Fset:  fset,
+		Files: files,
+		ResultOf: map[*analysis.Analyzer]interface{}{
+			inspect.Analyzer: inspector.New(files),
+			gomod.Analyzer:   gomod.Analyze(fset, files, gomod.Files(goMods)),
+		},
+	}
+	for _, analyzer := range []*analysis.Analyzer{packid.Analyzer, callgraph.Analyzer} {
+		res, err := analyzer.Run(pass)
+		assert.NoError(t, err)
+		pass.ResultOf[analyzer] = res
+	}
+	return pass.ResultOf[callgraph.Analyzer].(*callgraph.Result)
+}
+
+// runTypedAnalyzer type-checks the provided source, which must not import any packages, and runs the callgraph
+// analyzer over it using the resulting type information.
+func runTypedAnalyzer(t *testing.T, src string) *callgraph.Result {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "pkg/p.go", src, 0)
+	require.NoError(t, err)
+	files := []*ast.File{file}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	_, err = (&types.Config{}).Check("p", fset, files, info)
+	require.NoError(t, err)
+	outcomes, err := driver.Run(fset, files, info, []*analysis.Analyzer{callgraph.Analyzer}, func(analysis.Diagnostic) {})
+	require.NoError(t, err)
+	return outcomes[len(outcomes)-1].Result.(*callgraph.Result)
+}
@@ -0,0 +1,64 @@
+package callgraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PathGraph collects paths through the callgraph so they can be reported as a graphviz dot graph, which explains
+// to readers how a finding was reached. Each node is labeled with the name and arity of a signature, prefixed by the
+// package and the receiver of methods, when they are known.
+type PathGraph map[string]map[string]struct{}
+
+// AddPath adds each edge along the provided path to the graph.
+func (g PathGraph) AddPath(path []Signature) {
+	for i := 0; i < len(path); i++ {
+		from := pathLabel(path[i])
+		// put edge from -> to into graph
+		fromNeighbors, ok := g[from]
+		if !ok {
+			g[from] = make(map[string]struct{})
+			fromNeighbors = g[from]
+		}
+		if i != len(path)-1 {
+			to := pathLabel(path[i+1])
+			fromNeighbors[to] = struct{}{}
+		}
+	}
+}
+
+func pathLabel(sig Signature) string {
+	name := sig.Name
+	if sig.Recv != "" {
+		name = sig.Recv + "." + name
+	}
+	if sig.Package != "" {
+		name = sig.Package + "." + name
+	}
+	return fmt.Sprintf("(%s, %d)", name, sig.Arity)
+}
+
+// Report describes the graph as a graphviz dot graph of the paths which could lead to the function described by
+// badFuncPhrase.
+func (g PathGraph) Report(badFuncPhrase string) string {
+	var sb strings.Builder
+	if len(g) == 0 {
+		sb.WriteString("No path was found through the callgraph that could lead to a ")
+		sb.WriteString(badFuncPhrase)
+		sb.WriteString(".\n")
+		return sb.String()
+	}
+	sb.WriteString("The following graphviz dot graph describes paths through the callgraph that could lead to a ")
+	sb.WriteString(badFuncPhrase)
+	sb.WriteString(":\n")
+	sb.WriteString("digraph G {\n")
+	for from, neighbors := range g {
+		fmt.Fprintf(&sb, `  "%s" -> {`, from)
+		for to := range neighbors {
+			fmt.Fprintf(&sb, `"%s";`, to)
+		}
+		sb.WriteString("}\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
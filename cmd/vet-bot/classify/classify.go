@@ -0,0 +1,233 @@
+// Package classify tags each file read by VetBot as generated, vendored, test, example or normal code, using a
+// list of rules which can be read from a YAML file.
+package classify
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Class describes the kind of code found in a file.
+type Class uint8
+
+const (
+	// ClassNormal indicates a file which matched none of the rules.
+	ClassNormal Class = iota
+	// ClassTest indicates a test file.
+	ClassTest
+	// ClassExample indicates a file which only exists as an example of how to use some other code.
+	ClassExample
+	// ClassGenerated indicates a file which was generated by a tool.
+	ClassGenerated
+	// ClassVendored indicates a file copied from some other repository, either by vendoring or forking it.
+	ClassVendored
+)
+
+var classNames = []string{"normal", "test", "example", "generated", "vendored"}
+
+func (c Class) String() string {
+	if int(c) < len(classNames) {
+		return classNames[c]
+	}
+	return "unknown"
+}
+
+// ParseClass returns the Class with the provided name.
+func ParseClass(name string) (Class, error) {
+	for i, className := range classNames {
+		if className == name {
+			return Class(i), nil
+		}
+	}
+	return ClassNormal, fmt.Errorf("unknown class '%s'", name)
+}
+
+// UnmarshalYAML allows classes to be listed by name.
+func (c *Class) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err != nil {
+		return err
+	}
+	class, err := ParseClass(name)
+	if err != nil {
+		return err
+	}
+	*c = class
+	return nil
+}
+
+// Rule assigns a Class to every file which matches any of its paths, or whose header matches its header pattern.
+type Rule struct {
+	// Class is the class assigned to matching files.
+	Class Class `yaml:"class"`
+	// Paths lists glob patterns matched against the slash-separated path of each file, using the syntax of
+	// path.Match. Patterns ending in a slash match directories found anywhere in the path, e.g. "vendor/". Other
+	// patterns which contain a slash are matched against the entire path, and the rest are matched against the name
+	// of the file.
+	Paths []string `yaml:"paths,omitempty"`
+	// Header is a regular expression matched against each line of a file before its package clause.
+	Header string `yaml:"header,omitempty"`
+
+	header *regexp.Regexp
+}
+
+// matches returns true if the file found at the provided path with the provided header matches the rule.
+func (r Rule) matches(filepath string, header []string) bool {
+	for _, pattern := range r.Paths {
+		if matchPath(pattern, filepath) {
+			return true
+		}
+	}
+	if r.header != nil {
+		for _, line := range header {
+			if r.header.MatchString(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchPath matches the provided pattern against filepath, as described in the documentation for Rule.Paths.
+func matchPath(pattern, filepath string) bool {
+	if strings.HasSuffix(pattern, "/") {
+		dirs := strings.Split(path.Dir(filepath), "/")
+		want := strings.Split(strings.TrimSuffix(pattern, "/"), "/")
+		for i := 0; i+len(want) <= len(dirs); i++ {
+			if ok, _ := path.Match(strings.Join(want, "/"), strings.Join(dirs[i:i+len(want)], "/")); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		filepath = path.Base(filepath)
+	}
+	ok, _ := path.Match(pattern, filepath)
+	return ok
+}
+
+// Classifier classifies files using an ordered list of rules. The first rule which matches a file determines its
+// class. Findings are only reported to GitHub from files whose class is listed in Report.
+type Classifier struct {
+	Rules  []Rule  `yaml:"rules"`
+	Report []Class `yaml:"report"`
+}
+
+// Classify returns the class of the file found at the provided slash-separated path, which has the provided contents.
+func (c *Classifier) Classify(filepath string, contents []byte) Class {
+	header := readHeader(contents)
+	for _, rule := range c.Rules {
+		if rule.matches(filepath, header) {
+			return rule.Class
+		}
+	}
+	return ClassNormal
+}
+
+// ShouldReport returns true if findings in files of the provided class should be reported to GitHub.
+func (c *Classifier) ShouldReport(class Class) bool {
+	for _, report := range c.Report {
+		if report == class {
+			return true
+		}
+	}
+	return false
+}
+
+// readHeader returns the lines of the file which come before its package clause.
+func readHeader(contents []byte) []string {
+	var header []string
+	sc := bufio.NewScanner(bytes.NewReader(contents))
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		header = append(header, line)
+	}
+	return header
+}
+
+// Unmarshal unmarshals a Classifier from a YAML file.
+func Unmarshal(data []byte) (*Classifier, error) {
+	var c Classifier
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return nil, err
+	}
+	for i, rule := range c.Rules {
+		if rule.Header == "" {
+			continue
+		}
+		header, err := regexp.Compile(rule.Header)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header for rule %d: %w", i, err)
+		}
+		c.Rules[i].header = header
+	}
+	return &c, nil
+}
+
+// FromFile reads a Classifier from the provided YAML file.
+func FromFile(path string) (*Classifier, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(data)
+}
+
+// DefaultRules is the YAML file used to classify files when no other file is configured.
+const DefaultRules = `
+# findings are only reported to GitHub from files of these classes.
+report: [normal, example]
+rules:
+  - class: vendored
+    paths:
+      - vendor/
+      - third_party/
+      - Godeps/
+      - _vendor/
+      # copies of well-known libraries, laid out by import path.
+      - github.com/*/*/
+      - golang.org/x/*/
+      - gopkg.in/*/
+      - google.golang.org/*/
+      - k8s.io/*/
+  - class: generated
+    header: '^// Code generated .* DO NOT EDIT\.$'
+    paths:
+      - '*.pb.go'
+      - '*.pb.gw.go'
+      - 'zz_generated.*.go'
+      - 'bindata.go'
+      - '*_bindata.go'
+      - 'mock_*.go'
+      - '*_mock.go'
+  - class: test
+    paths:
+      - '*_test.go'
+      - testdata/
+  - class: example
+    paths:
+      - 'example_*.go'
+      - example/
+      - examples/
+      - _examples/
+`
+
+// Default returns a Classifier which uses DefaultRules.
+func Default() *Classifier {
+	c, err := Unmarshal([]byte(DefaultRules))
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
@@ -0,0 +1,81 @@
+package classify
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const generatedHeader = `// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: api.proto
+
+package api
+`
+
+func TestDefault(t *testing.T) {
+	tests := []struct {
+		path     string
+		contents string
+		expected Class
+	}{
+		{"main.go", "package main\n", ClassNormal},
+		{"pkg/api/api.go", generatedHeader, ClassGenerated},
+		{"pkg/api/api.go", "package api\n\n// Code generated by protoc-gen-go. DO NOT EDIT.\n", ClassNormal},
+		{"pkg/api/api.pb.go", "package api\n", ClassGenerated},
+		{"pkg/apis/v1/zz_generated.deepcopy.go", "package v1\n", ClassGenerated},
+		{"assets/bindata.go", "package assets\n", ClassGenerated},
+		{"store/mock_store.go", "package store\n", ClassGenerated},
+		{"vendor/github.com/pkg/errors/errors.go", "package errors\n", ClassVendored},
+		{"vendor/github.com/pkg/errors/errors_test.go", "package errors\n", ClassVendored},
+		{"lib/third_party/yaml/decode.go", "package yaml\n", ClassVendored},
+		{"src/github.com/gorilla/mux/mux.go", "package mux\n", ClassVendored},
+		{"github.com/main.go", "package main\n", ClassNormal},
+		{"pkg/server/server_test.go", "package server\n", ClassTest},
+		{"pkg/server/testdata/fixture.go", "package fixture\n", ClassTest},
+		{"pkg/server/example_test.go", "package server\n", ClassTest},
+		{"pkg/server/example_server.go", "package server\n", ClassExample},
+		{"examples/hello/main.go", "package main\n", ClassExample},
+		{"vendor.go", "package main\n", ClassNormal},
+	}
+	c := Default()
+	for _, test := range tests {
+		assert.Equal(t, test.expected, c.Classify(test.path, []byte(test.contents)), test.path)
+	}
+	assert.True(t, c.ShouldReport(ClassNormal))
+	assert.True(t, c.ShouldReport(ClassExample))
+	assert.False(t, c.ShouldReport(ClassTest))
+	assert.False(t, c.ShouldReport(ClassVendored))
+	assert.False(t, c.ShouldReport(ClassGenerated))
+}
+
+func TestFromFile(t *testing.T) {
+	c, err := FromFile("testdata/rules.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, ClassGenerated, c.Classify("enum.go", []byte("// Code generated by stringer -type=Kind; DO NOT EDIT.\n\npackage enum\n")))
+	assert.Equal(t, ClassNormal, c.Classify("api.pb.go", []byte("package api\n")))
+	assert.Equal(t, ClassVendored, c.Classify("forks/mux/mux.go", []byte("package mux\n")))
+	assert.Equal(t, ClassExample, c.Classify("pkg/demo_client.go", []byte("package pkg\n")))
+	assert.Equal(t, ClassExample, c.Classify("cmd/demo/main.go", []byte("package main\n")))
+	assert.Equal(t, ClassNormal, c.Classify("cmd/other/cmd/demo/main.go", []byte("package main\n")))
+	assert.True(t, c.ShouldReport(ClassNormal))
+	assert.False(t, c.ShouldReport(ClassExample))
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	_, err := Unmarshal([]byte("rules:\n  - class: handwritten\n"))
+	assert.Error(t, err)
+	_, err = Unmarshal([]byte("rules:\n  - class: generated\n    header: '('\n"))
+	assert.Error(t, err)
+	_, err = Unmarshal([]byte("rules:\n  - class: generated\n    path: '*.pb.go'\n"))
+	assert.Error(t, err, "unknown fields should not be silently ignored")
+}
+
+func TestParseClass(t *testing.T) {
+	for _, class := range []Class{ClassNormal, ClassTest, ClassExample, ClassGenerated, ClassVendored} {
+		parsed, err := ParseClass(class.String())
+		assert.NoError(t, err)
+		assert.Equal(t, class, parsed)
+	}
+	_, err := ParseClass("unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,101 @@
+// Package driver runs a set of analyzers over a set of parsed files, without relying on the type-checker or the
+// go/packages loader.
+package driver
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Outcome records the result of running a single analyzer.
+type Outcome struct {
+	Analyzer *analysis.Analyzer
+	Result   interface{}
+	Err      error
+}
+
+// Run runs the provided analyzers, along with every analyzer they require, over the provided files. Each analyzer
+// runs after all of its requirements, and sees only their results in Pass.ResultOf. If an analyzer fails, every
+// analyzer which depends on it is skipped, but analyzers which do not depend on it continue to run. info is passed
+// along to each analyzer as Pass.TypesInfo, and may be nil if the files were not type-checked.
+//
+// Diagnostics are passed to report with their Category set to the name of the analyzer which reported them, unless
+// the analyzer set a Category itself. One Outcome is returned for each analyzer, in the order they were run.
+func Run(fset *token.FileSet, files []*ast.File, info *types.Info, analyzers []*analysis.Analyzer, report func(analysis.Diagnostic)) ([]Outcome, error) {
+	return RunWithInputs(fset, files, info, nil, analyzers, report)
+}
+
+// RunWithInputs runs the provided analyzers in the same way as Run, except that analyzers found in inputs are not
+// run. Instead, their result is taken from inputs, and passed along to the analyzers which require them. Inputs
+// provide data which is not found in the files themselves, such as the contents of go.mod files, without resorting to
+// global state.
+func RunWithInputs(fset *token.FileSet, files []*ast.File, info *types.Info, inputs map[*analysis.Analyzer]interface{},
+	analyzers []*analysis.Analyzer, report func(analysis.Diagnostic)) ([]Outcome, error) {
+	if err := analysis.Validate(analyzers); err != nil {
+		return nil, err
+	}
+	order := sortAnalyzers(analyzers)
+	outcomes := make(map[*analysis.Analyzer]*Outcome, len(order))
+	result := make([]Outcome, 0, len(order))
+	for _, a := range order {
+		outcome := Outcome{Analyzer: a}
+		if input, ok := inputs[a]; ok {
+			outcome.Result = input
+		} else {
+			outcome.Result, outcome.Err = runAnalyzer(a, fset, files, info, report, outcomes)
+		}
+		outcomes[a] = &outcome
+		result = append(result, outcome)
+	}
+	return result, nil
+}
+
+func runAnalyzer(a *analysis.Analyzer, fset *token.FileSet, files []*ast.File, info *types.Info,
+	report func(analysis.Diagnostic), outcomes map[*analysis.Analyzer]*Outcome) (interface{}, error) {
+	pass := analysis.Pass{
+		Analyzer:  a,
+		Fset:      fset,
+		Files:     files,
+		TypesInfo: info,
+		ResultOf:  make(map[*analysis.Analyzer]interface{}, len(a.Requires)),
+		Report: func(d analysis.Diagnostic) {
+			if d.Category == "" {
+				d.Category = a.Name
+			}
+			report(d)
+		},
+	}
+	for _, req := range a.Requires {
+		if outcomes[req].Err != nil {
+			return nil, fmt.Errorf("skipped since required analyzer %s failed", req.Name)
+		}
+		pass.ResultOf[req] = outcomes[req].Result
+	}
+	return a.Run(&pass)
+}
+
+// sortAnalyzers returns the provided analyzers and all of their requirements, sorted so that each analyzer appears
+// after all of its requirements. analysis.Validate must have been called beforehand to rule out cycles.
+func sortAnalyzers(analyzers []*analysis.Analyzer) []*analysis.Analyzer {
+	var result []*analysis.Analyzer
+	visited := make(map[*analysis.Analyzer]struct{})
+	var visit func(a *analysis.Analyzer)
+	visit = func(a *analysis.Analyzer) {
+		if _, ok := visited[a]; ok {
+			return
+		}
+		visited[a] = struct{}{}
+		for _, req := range a.Requires {
+			visit(req)
+		}
+		result = append(result, a)
+	}
+	for _, a := range analyzers {
+		visit(a)
+	}
+	return result
+}
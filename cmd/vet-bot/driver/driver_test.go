@@ -0,0 +1,68 @@
+package driver
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
+)
+
+func analyzer(name string, run func(*analysis.Pass) (interface{}, error), requires ...*analysis.Analyzer) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name:     name,
+		Doc:      name,
+		Run:      run,
+		Requires: requires,
+	}
+}
+
+func returns(value string) func(*analysis.Pass) (interface{}, error) {
+	return func(*analysis.Pass) (interface{}, error) { return value, nil }
+}
+
+func TestRun(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "a.go", "package a", 0)
+	assert.NoError(t, err)
+
+	base := analyzer("base", returns("base"))
+	failing := analyzer("failing", func(*analysis.Pass) (interface{}, error) {
+		return nil, errors.New("oops")
+	}, base)
+	dependent := analyzer("dependent", returns("dependent"), failing)
+	var seen interface{}
+	independent := analyzer("independent", func(pass *analysis.Pass) (interface{}, error) {
+		seen = pass.ResultOf[base]
+		pass.Report(analysis.Diagnostic{Message: "found"})
+		return nil, nil
+	}, base)
+
+	var diagnostics []analysis.Diagnostic
+	outcomes, err := Run(fset, []*ast.File{file}, nil, []*analysis.Analyzer{dependent, independent}, func(d analysis.Diagnostic) {
+		diagnostics = append(diagnostics, d)
+	})
+	assert.NoError(t, err)
+
+	var order []string
+	for _, outcome := range outcomes {
+		order = append(order, outcome.Analyzer.Name)
+	}
+	assert.Equal(t, []string{"base", "failing", "dependent", "independent"}, order)
+	assert.Equal(t, "base", outcomes[0].Result)
+	assert.EqualError(t, outcomes[1].Err, "oops")
+	assert.Error(t, outcomes[2].Err)
+	assert.NoError(t, outcomes[3].Err)
+	assert.Equal(t, "base", seen)
+	if assert.Len(t, diagnostics, 1) {
+		assert.Equal(t, "independent", diagnostics[0].Category)
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	_, err := Run(token.NewFileSet(), nil, nil, []*analysis.Analyzer{analyzer("not valid", returns(""))}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,60 @@
+// Package drivertest runs analyzers over the packages found in testdata through the driver, without type
+// information, in the same way vet-bot runs them by default.
+package drivertest
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/github-vet/bots/cmd/vet-bot/driver"
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Run parses every .go file found in the provided directory, and runs the provided analyzer over them without type
+// information. If the directory contains a go.mod file, it is passed to the gomod analyzer as an input; go.mod files
+// found outside of the directory, such as the one declaring the module under test, are never read. The messages of
+// any diagnostics reported are returned in the order they were reported.
+func Run(t *testing.T, dir string, analyzer *analysis.Analyzer) []string {
+	t.Helper()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("cannot read directory %s: %v", dir, err)
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	goMods := make(map[string][]byte)
+	for _, info := range infos {
+		filename := filepath.ToSlash(filepath.Join(dir, info.Name()))
+		if info.Name() == "go.mod" {
+			if goMods[filename], err = ioutil.ReadFile(filename); err != nil {
+				t.Fatalf("cannot read %s: %v", filename, err)
+			}
+			continue
+		}
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filename, nil, 0)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %v", filename, err)
+		}
+		files = append(files, file)
+	}
+	inputs := map[*analysis.Analyzer]interface{}{
+		gomod.Analyzer: gomod.Analyze(fset, files, gomod.Files(goMods)),
+	}
+	var reported []string
+	_, err = driver.RunWithInputs(fset, files, nil, inputs, []*analysis.Analyzer{analyzer}, func(d analysis.Diagnostic) {
+		reported = append(reported, d.Message)
+	})
+	if err != nil {
+		t.Fatalf("cannot run %s: %v", analyzer.Name, err)
+	}
+	return reported
+}
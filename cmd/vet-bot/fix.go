@@ -0,0 +1,107 @@
+package main
+
+import (
+	"go/format"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/github-vet/bots/cmd/vet-bot/fixes"
+	"golang.org/x/tools/go/analysis"
+)
+
+// suggestedFixDiff describes the first fix suggested by the provided diagnostic as a unified diff. It returns the
+// empty string if no fix was suggested, or if the fix can't be applied to the contents of the file.
+func suggestedFixDiff(fset *token.FileSet, d analysis.Diagnostic, contents map[string][]byte) string {
+	if len(d.SuggestedFixes) == 0 {
+		return ""
+	}
+	filename, edits, err := fixes.Edits(fset, d.SuggestedFixes[0])
+	if err != nil || filename == "" {
+		return ""
+	}
+	diff, err := fixes.Diff(filename, contents[filename], edits)
+	if err != nil {
+		log.Printf("cannot describe fix suggested in %s: %v", filename, err)
+		return ""
+	}
+	return diff
+}
+
+// fixRoot returns the directory in which suggested fixes are applied. ok is false unless -fix was passed and the
+// source being vetted is a local directory.
+func fixRoot(bot *VetBot, src Source) (root string, ok bool) {
+	if !bot.opts.Fix {
+		return "", false
+	}
+	dir, ok := src.(*LocalDirSource)
+	if !ok {
+		log.Printf("fixes can only be applied to a local directory; ignoring -fix")
+		return "", false
+	}
+	return dir.Root, true
+}
+
+// Fixer collects the fixes suggested for each finding, and applies them to the files on disk.
+type Fixer struct {
+	fset  *token.FileSet
+	edits map[string][][]fixes.Edit // the edits of each fix suggested in each file, in the order they were reported
+}
+
+// NewFixer creates a Fixer for files parsed into the provided FileSet.
+func NewFixer(fset *token.FileSet) *Fixer {
+	return &Fixer{fset: fset, edits: make(map[string][][]fixes.Edit)}
+}
+
+// Collect wraps the provided Reporter, recording the first fix suggested by each diagnostic before passing it along.
+func (f *Fixer) Collect(next Reporter) Reporter {
+	return func(contents map[string][]byte) func(analysis.Diagnostic) {
+		report := next(contents)
+		return func(d analysis.Diagnostic) {
+			if len(d.SuggestedFixes) > 0 {
+				filename, edits, err := fixes.Edits(f.fset, d.SuggestedFixes[0])
+				if err != nil {
+					log.Printf("cannot apply fix suggested at %v: %v", f.fset.Position(d.Pos), err)
+				} else if filename != "" {
+					f.edits[filename] = append(f.edits[filename], edits)
+				}
+			}
+			report(d)
+		}
+	}
+}
+
+// Apply applies every fix collected to the files found under root. Fixes which overlap a fix suggested earlier in
+// the same file are skipped. Each file which is changed is formatted with gofmt, provided it still parses.
+func (f *Fixer) Apply(root string, contents map[string][]byte) {
+	var filenames []string
+	for filename := range f.edits {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+	for _, filename := range filenames {
+		edits, skipped := fixes.Merge(f.edits[filename])
+		fixed, err := fixes.Apply(contents[filename], edits)
+		if err != nil {
+			log.Printf("cannot apply fixes to %s: %v", filename, err)
+			continue
+		}
+		if formatted, err := format.Source(fixed); err == nil {
+			fixed = formatted
+		}
+		path := filepath.Join(root, filepath.FromSlash(filename))
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Printf("cannot apply fixes to %s: %v", filename, err)
+			continue
+		}
+		if err := ioutil.WriteFile(path, fixed, info.Mode()); err != nil {
+			log.Printf("cannot apply fixes to %s: %v", filename, err)
+			continue
+		}
+		log.Printf("applied %d fix(es) to %s; skipped %d overlapping fix(es)", len(f.edits[filename])-skipped, filename, skipped)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
+)
+
+const fixTestSource = `package p
+
+var ptr *int
+
+func f(xs []int) {
+	for _, x := range xs {
+		ptr = &x
+	}
+}
+`
+
+func TestFixer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vetbot-fix")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "p.go"), []byte(fixTestSource), 0644))
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", fixTestSource, parser.AllErrors)
+	assert.NoError(t, err)
+	contents := map[string][]byte{"p.go": []byte(fixTestSource)}
+
+	var diffs []string
+	fixer := NewFixer(fset)
+	VetRepo(contents, nil, []*ast.File{file}, fset, nil, fixer.Collect(func(contents map[string][]byte) func(analysis.Diagnostic) {
+		return func(d analysis.Diagnostic) {
+			diffs = append(diffs, suggestedFixDiff(fset, d, contents))
+		}
+	}))
+	fixer.Apply(dir, contents)
+
+	assert.Equal(t, []string{`--- a/p.go
++++ b/p.go
+@@ -3,7 +3,7 @@
+ var ptr *int
+ 
+ func f(xs []int) {
+-	for _, x := range xs {
+-		ptr = &x
++	for i := range xs {
++		ptr = &xs[i]
+ 	}
+ }
+`}, diffs)
+	fixed, err := ioutil.ReadFile(filepath.Join(dir, "p.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(fixed), "\tfor i := range xs {\n\t\tptr = &xs[i]\n")
+}
+
+func TestFixRoot(t *testing.T) {
+	_, ok := fixRoot(&VetBot{opts: opts{Fix: false}}, &LocalDirSource{Root: "dir"})
+	assert.False(t, ok)
+	_, ok = fixRoot(&VetBot{opts: opts{Fix: true}}, &LocalTarballSource{Path: "repo.tar.gz"})
+	assert.False(t, ok)
+	root, ok := fixRoot(&VetBot{opts: opts{Fix: true}}, &LocalDirSource{Root: "dir"})
+	assert.True(t, ok)
+	assert.Equal(t, "dir", root)
+}
@@ -0,0 +1,342 @@
+// Package fixes builds the fixes suggested by VetBot's analyzers, applies them to source code, and describes them
+// as unified diffs.
+package fixes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// ErrOverlap is returned when two different edits replace overlapping ranges of the same file.
+var ErrOverlap = errors.New("edits overlap")
+
+// Edit replaces the bytes between the offsets Start and End of a file with NewText.
+type Edit struct {
+	Start, End int
+	NewText    string
+}
+
+// Edits converts the text edits of a SuggestedFix to byte offsets. Every edit must apply to the same file, whose
+// name is returned.
+func Edits(fset *token.FileSet, fix analysis.SuggestedFix) (string, []Edit, error) {
+	var file *token.File
+	var edits []Edit
+	for _, edit := range fix.TextEdits {
+		end := edit.End
+		if end == token.NoPos {
+			end = edit.Pos
+		}
+		f := fset.File(edit.Pos)
+		if f == nil || fset.File(end) != f {
+			return "", nil, fmt.Errorf("edit at %v does not apply to a single file", fset.Position(edit.Pos))
+		}
+		if file != nil && f != file {
+			return "", nil, fmt.Errorf("fix applies to both %s and %s", file.Name(), f.Name())
+		}
+		file = f
+		edits = append(edits, Edit{Start: f.Offset(edit.Pos), End: f.Offset(end), NewText: string(edit.NewText)})
+	}
+	if file == nil {
+		return "", nil, nil
+	}
+	return file.Name(), edits, nil
+}
+
+// normalize sorts the provided edits and removes exact duplicates. ErrOverlap is returned if any two of the
+// remaining edits overlap.
+func normalize(edits []Edit) ([]Edit, error) {
+	sorted := make([]Edit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Start != sorted[j].Start {
+			return sorted[i].Start < sorted[j].Start
+		}
+		return sorted[i].End < sorted[j].End
+	})
+	var result []Edit
+	for _, edit := range sorted {
+		if edit.End < edit.Start {
+			return nil, fmt.Errorf("edit at offset %d ends before it starts", edit.Start)
+		}
+		if len(result) > 0 {
+			last := result[len(result)-1]
+			if last == edit {
+				continue
+			}
+			// two insertions at the same offset are ambiguous, and count as overlapping.
+			if edit.Start < last.End || edit.Start == last.Start {
+				return nil, ErrOverlap
+			}
+		}
+		result = append(result, edit)
+	}
+	return result, nil
+}
+
+// Apply applies the provided edits to src. Exact duplicates are applied only once.
+func Apply(src []byte, edits []Edit) ([]byte, error) {
+	edits, err := normalize(edits)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	last := 0
+	for _, edit := range edits {
+		if edit.End > len(src) {
+			return nil, fmt.Errorf("edit at offset %d is past the end of the file", edit.Start)
+		}
+		b.Write(src[last:edit.Start])
+		b.WriteString(edit.NewText)
+		last = edit.End
+	}
+	b.Write(src[last:])
+	return b.Bytes(), nil
+}
+
+// Merge combines several sets of edits, such as the fixes suggested for different findings in the same file. Each
+// set is either merged entirely or skipped, if it overlaps with a set merged before it. The number of sets skipped
+// is returned.
+func Merge(sets [][]Edit) ([]Edit, int) {
+	var merged []Edit
+	skipped := 0
+	for _, set := range sets {
+		candidate := append(append([]Edit(nil), merged...), set...)
+		if _, err := normalize(candidate); err != nil {
+			skipped++
+			continue
+		}
+		merged = candidate
+	}
+	return merged, skipped
+}
+
+// contextLines is the number of unchanged lines shown around each change in a diff.
+const contextLines = 3
+
+// chunk replaces the lines [start, end) of the original file with lines.
+type chunk struct {
+	start, end int
+	lines      []string
+}
+
+// Diff describes the result of applying the provided edits to src as a unified diff, using filename as the name
+// of both the original and the modified file.
+func Diff(filename string, src []byte, edits []Edit) (string, error) {
+	edits, err := normalize(edits)
+	if err != nil {
+		return "", err
+	}
+	lines := splitLines(string(src))
+	starts := make([]int, len(lines)+1)
+	for i, line := range lines {
+		starts[i+1] = starts[i] + len(line)
+	}
+	lineOf := func(offset int) int {
+		line := sort.Search(len(starts), func(i int) bool { return starts[i] > offset }) - 1
+		if line >= len(lines) {
+			line = len(lines) - 1
+		}
+		if line < 0 {
+			line = 0
+		}
+		return line
+	}
+
+	// group edits which touch the same or adjacent lines into chunks of whole lines.
+	var chunks []chunk
+	if len(lines) == 0 {
+		applied, err := Apply(src, edits)
+		if err != nil {
+			return "", err
+		}
+		chunks = append(chunks, chunk{lines: splitLines(string(applied))})
+	}
+	for i := 0; i < len(edits) && len(lines) > 0; {
+		first, last := lineOf(edits[i].Start), lineOf(edits[i].End)
+		j := i + 1
+		for ; j < len(edits) && lineOf(edits[j].Start) <= last+1; j++ {
+			if end := lineOf(edits[j].End); end > last {
+				last = end
+			}
+		}
+		var b strings.Builder
+		pos := starts[first]
+		for _, edit := range edits[i:j] {
+			b.Write(src[pos:edit.Start])
+			b.WriteString(edit.NewText)
+			pos = edit.End
+		}
+		b.Write(src[pos:starts[last+1]])
+		if c := trim(chunk{start: first, end: last + 1, lines: splitLines(b.String())}, lines); c.start < c.end || len(c.lines) > 0 {
+			chunks = append(chunks, c)
+		}
+		i = j
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", filename, filename)
+	delta := 0
+	for i := 0; i < len(chunks); {
+		// chunks separated by fewer than twice the context are shown in the same hunk.
+		j := i + 1
+		for j < len(chunks) && chunks[j].start-chunks[j-1].end <= 2*contextLines {
+			j++
+		}
+		from := max(chunks[i].start-contextLines, 0)
+		to := min(chunks[j-1].end+contextLines, len(lines))
+		var body strings.Builder
+		added, removed := 0, 0
+		pos := from
+		for _, c := range chunks[i:j] {
+			writeLines(&body, " ", lines[pos:c.start])
+			writeLines(&body, "-", lines[c.start:c.end])
+			writeLines(&body, "+", c.lines)
+			removed += c.end - c.start
+			added += len(c.lines)
+			pos = c.end
+		}
+		writeLines(&body, " ", lines[pos:to])
+		oldCount := to - from
+		newCount := oldCount - removed + added
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n%s", hunkRange(from, oldCount), hunkRange(from+delta, newCount), body.String())
+		delta += added - removed
+		i = j
+	}
+	return b.String(), nil
+}
+
+// trim removes the lines at the start and end of the chunk which are left unchanged.
+func trim(c chunk, lines []string) chunk {
+	for c.start < c.end && len(c.lines) > 0 && lines[c.start] == c.lines[0] {
+		c.start++
+		c.lines = c.lines[1:]
+	}
+	for c.start < c.end && len(c.lines) > 0 && lines[c.end-1] == c.lines[len(c.lines)-1] {
+		c.end--
+		c.lines = c.lines[:len(c.lines)-1]
+	}
+	return c
+}
+
+// hunkRange formats the range of a hunk header; empty ranges refer to the line before the hunk.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func writeLines(b *strings.Builder, prefix string, lines []string) {
+	for _, line := range lines {
+		b.WriteString(prefix + line)
+		if !strings.HasSuffix(line, "\n") {
+			b.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// splitLines splits s into lines, each of which keeps its trailing newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// CopyVars returns an edit which declares a copy of each of the named variables before the statement found at pos,
+// as in `v := v`. The statement is assumed to be indented with tabs.
+func CopyVars(fset *token.FileSet, pos token.Pos, names []string) analysis.TextEdit {
+	indent := strings.Repeat("\t", fset.Position(pos).Column-1)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s := %s\n%s", name, name, indent)
+	}
+	return analysis.TextEdit{Pos: pos, End: pos, NewText: []byte(b.String())}
+}
+
+// Mentions returns true if the provided identifier name is used anywhere within node.
+func Mentions(node ast.Node, name string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == name {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// Assigns returns true if the variable described by obj may be assigned to within node; either directly, or
+// via a reference taken to it.
+func Assigns(node ast.Node, obj *ast.Object) bool {
+	return assigns(node, obj, true)
+}
+
+// AssignsDirectly returns true if the variable described by obj is assigned to within node, ignoring any references
+// taken to it.
+func AssignsDirectly(node ast.Node, obj *ast.Object) bool {
+	return assigns(node, obj, false)
+}
+
+func assigns(node ast.Node, obj *ast.Object, viaReference bool) bool {
+	refersTo := func(expr ast.Expr) bool {
+		id, ok := expr.(*ast.Ident)
+		return ok && id.Obj == obj
+	}
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch typed := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range typed.Lhs {
+				if refersTo(lhs) {
+					found = true
+				}
+			}
+		case *ast.IncDecStmt:
+			found = found || refersTo(typed.X)
+		case *ast.UnaryExpr:
+			found = found || (viaReference && typed.Op == token.AND && refersTo(typed.X))
+		}
+		return !found
+	})
+	return found
+}
+
+// Declares returns true if a variable with the provided name is declared anywhere within node.
+func Declares(node ast.Node, name string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Name == name && id.Obj != nil && id.Obj.Decl != nil {
+			if id.Obj.Pos() == id.Pos() {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,118 @@
+package fixes
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
+)
+
+const src = `package p
+
+func f(xs []int) {
+	for _, x := range xs {
+		use(&x)
+	}
+}
+`
+
+func TestApply(t *testing.T) {
+	out, err := Apply([]byte("abcdef"), []Edit{
+		{Start: 4, End: 5, NewText: "E"},
+		{Start: 1, End: 1, NewText: "+"},
+		{Start: 1, End: 1, NewText: "+"}, // exact duplicates are applied once.
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "a+bcdEf", string(out))
+
+	_, err = Apply([]byte("abcdef"), []Edit{{Start: 1, End: 3}, {Start: 2, End: 4}})
+	assert.Equal(t, ErrOverlap, err)
+	_, err = Apply([]byte("abcdef"), []Edit{{Start: 1, End: 1, NewText: "x"}, {Start: 1, End: 1, NewText: "y"}})
+	assert.Equal(t, ErrOverlap, err)
+}
+
+func TestMerge(t *testing.T) {
+	merged, skipped := Merge([][]Edit{
+		{{Start: 0, End: 1, NewText: "A"}},
+		{{Start: 0, End: 2, NewText: "AB"}, {Start: 4, End: 4, NewText: "!"}},
+		{{Start: 0, End: 1, NewText: "A"}, {Start: 2, End: 3, NewText: "C"}},
+	})
+	assert.Equal(t, 1, skipped)
+	out, err := Apply([]byte("abcd"), merged)
+	assert.NoError(t, err)
+	assert.Equal(t, "AbCd", string(out))
+}
+
+func TestEditsAndDiff(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	assert.NoError(t, err)
+	loop := file.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.RangeStmt)
+	use := loop.Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr).Args[0].(*ast.UnaryExpr).X
+
+	filename, edits, err := Edits(fset, analysis.SuggestedFix{TextEdits: []analysis.TextEdit{
+		CopyVars(fset, loop.Body.List[0].Pos(), []string{"x"}),
+		{Pos: use.Pos(), End: use.End(), NewText: []byte("y")},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "p.go", filename)
+
+	out, err := Apply([]byte(src), edits)
+	assert.NoError(t, err)
+	assert.Equal(t, "package p\n\nfunc f(xs []int) {\n\tfor _, x := range xs {\n\t\tx := x\n\t\tuse(&y)\n\t}\n}\n", string(out))
+
+	diff, err := Diff(filename, []byte(src), edits)
+	assert.NoError(t, err)
+	assert.Equal(t, `--- a/p.go
++++ b/p.go
+@@ -2,6 +2,7 @@
+ 
+ func f(xs []int) {
+ 	for _, x := range xs {
+-		use(&x)
++		x := x
++		use(&y)
+ 	}
+ }
+`, diff)
+}
+
+func TestDiffSeparateHunks(t *testing.T) {
+	var lines string
+	for i := 0; i < 20; i++ {
+		lines += "line\n"
+	}
+	diff, err := Diff("f.txt", []byte(lines), []Edit{{Start: 0, End: 0, NewText: "first\n"}, {Start: 95, End: 100, NewText: ""}})
+	assert.NoError(t, err)
+	assert.Equal(t, `--- a/f.txt
++++ b/f.txt
+@@ -1,3 +1,4 @@
++first
+ line
+ line
+ line
+@@ -17,4 +18,3 @@
+ line
+ line
+ line
+-line
+`, diff)
+}
+
+func TestAssigns(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	assert.NoError(t, err)
+	loop := file.Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.RangeStmt)
+	x := loop.Value.(*ast.Ident)
+	assert.True(t, Assigns(loop.Body, x.Obj))
+	assert.False(t, Assigns(loop.Body, loop.X.(*ast.Ident).Obj))
+	assert.False(t, AssignsDirectly(loop.Body, x.Obj))
+	assert.True(t, Mentions(loop.Body, "use"))
+	assert.False(t, Mentions(loop.Body, "i"))
+	assert.False(t, Declares(loop.Body, "x"))
+	assert.True(t, Declares(loop, "x"))
+}
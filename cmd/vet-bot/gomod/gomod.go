@@ -0,0 +1,194 @@
+// Package gomod finds the module which contains each file being analyzed, along with the version of the Go language
+// declared in its go.mod file.
+package gomod
+
+import (
+	"bufio"
+	"bytes"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Analyzer finds the innermost module containing each file. Unless its -read flag is set, no go.mod files are read,
+// and every file is assumed to predate Go 1.22. Drivers which hold the files being analyzed in memory should instead
+// provide the result of Analyze as an input to the driver.
+var Analyzer = &analysis.Analyzer{
+	Name:             "gomod",
+	Doc:              "finds the innermost module containing each file, along with the Go version it declares",
+	Run:              run,
+	RunDespiteErrors: true,
+	ResultType:       reflect.TypeOf((*Result)(nil)),
+}
+
+// readFromDisk is set by the -read flag of the Analyzer.
+var readFromDisk bool
+
+func init() {
+	Analyzer.Flags.BoolVar(&readFromDisk, "read", false, "read the go.mod files containing each file from disk")
+}
+
+// Module describes a module found in a go.mod file.
+type Module struct {
+	// Dir is the slash-separated directory containing the go.mod file.
+	Dir string
+	// Path is the module path declared in the go.mod file.
+	Path string
+	// GoVersion is the version of the Go language declared in the go.mod file, e.g. "1.22". It is empty if no
+	// version is declared.
+	GoVersion string
+}
+
+// PerIterationLoopVars returns true if the module declares a version of Go in which each iteration of a loop has its
+// own copy of the loop variables, which is the case from Go 1.22 onwards.
+func (m *Module) PerIterationLoopVars() bool {
+	return m != nil && AtLeast(m.GoVersion, 1, 22)
+}
+
+// DirFor returns the slash-separated directory containing the package with the provided import path, if the package
+// belongs to the module.
+func (m *Module) DirFor(importPath string) (string, bool) {
+	if m == nil || m.Path == "" {
+		return "", false
+	}
+	if importPath == m.Path {
+		return m.Dir, true
+	}
+	if !strings.HasPrefix(importPath, m.Path+"/") {
+		return "", false
+	}
+	return path.Join(m.Dir, strings.TrimPrefix(importPath, m.Path+"/")), true
+}
+
+// Result maps each file to the innermost module containing it.
+type Result struct {
+	fset    *token.FileSet
+	modules map[*token.File]*Module
+}
+
+// ModuleFor returns the innermost module containing the provided position, or nil if none was found.
+func (r *Result) ModuleFor(pos token.Pos) *Module {
+	if r == nil {
+		return nil
+	}
+	return r.modules[r.fset.File(pos)]
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if !readFromDisk {
+		return Analyze(pass.Fset, pass.Files, nil), nil
+	}
+	return Analyze(pass.Fset, pass.Files, ReadFile), nil
+}
+
+// Analyze finds the innermost module containing each of the provided files. readFile is called with the
+// slash-separated path of each go.mod file which may contain a file, and should return an error if the file does not
+// exist. If readFile is nil, no go.mod files are read, and every file is assumed to predate Go 1.22.
+func Analyze(fset *token.FileSet, files []*ast.File, readFile func(path string) ([]byte, error)) *Result {
+	result := &Result{
+		fset:    fset,
+		modules: make(map[*token.File]*Module),
+	}
+	if readFile == nil {
+		return result
+	}
+	byDir := make(map[string]*Module) // caches the innermost module found for each directory
+	for _, file := range files {
+		tokFile := fset.File(file.Pos())
+		if tokFile == nil {
+			continue
+		}
+		result.modules[tokFile] = findModule(path.Dir(filepath.ToSlash(tokFile.Name())), readFile, byDir)
+	}
+	return result
+}
+
+// ReadFile reads the go.mod file found at the provided slash-separated path from disk.
+func ReadFile(path string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.FromSlash(path))
+}
+
+// Files returns a function which reads go.mod files from the provided map, which maps the slash-separated path of
+// each go.mod file to its contents.
+func Files(goMods map[string][]byte) func(path string) ([]byte, error) {
+	return func(path string) ([]byte, error) {
+		if data, ok := goMods[path]; ok {
+			return data, nil
+		}
+		return nil, os.ErrNotExist
+	}
+}
+
+// findModule walks up the directory tree from dir until a go.mod file is found.
+func findModule(dir string, readFile func(path string) ([]byte, error), byDir map[string]*Module) *Module {
+	if mod, ok := byDir[dir]; ok {
+		return mod
+	}
+	var mod *Module
+	if data, err := readFile(path.Join(dir, "go.mod")); err == nil {
+		mod = Parse(data)
+		mod.Dir = dir
+	} else if parent := path.Dir(dir); parent != dir {
+		mod = findModule(parent, readFile, byDir)
+	}
+	byDir[dir] = mod
+	return mod
+}
+
+// Parse reads the module path and Go version from the contents of a go.mod file.
+func Parse(data []byte) *Module {
+	result := &Module{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := sc.Text()
+		if idx := strings.Index(line, "//"); idx != -1 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			result.Path = strings.Trim(fields[1], `"`)
+		case "go":
+			result.GoVersion = fields[1]
+		}
+	}
+	return result
+}
+
+// AtLeast returns true if the provided Go version, e.g. "1.21" or "1.22.1", is at least major.minor.
+func AtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	vMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	vMinor, err := strconv.Atoi(leadingDigits(parts[1]))
+	if err != nil {
+		return false
+	}
+	return vMajor > major || (vMajor == major && vMinor >= minor)
+}
+
+// leadingDigits strips suffixes such as "rc1" from a version component.
+func leadingDigits(str string) string {
+	for i, r := range str {
+		if r < '0' || r > '9' {
+			return str[:i]
+		}
+	}
+	return str
+}
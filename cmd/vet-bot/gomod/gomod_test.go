@@ -0,0 +1,78 @@
+package gomod
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
+)
+
+func TestParse(t *testing.T) {
+	mod := Parse([]byte("// comment\nmodule \"github.com/owner/repo\"\n\ngo 1.22 // trailing\n\nrequire (\n\tfoo v1.0.0\n)\n"))
+	assert.Equal(t, "github.com/owner/repo", mod.Path)
+	assert.Equal(t, "1.22", mod.GoVersion)
+}
+
+func TestAtLeast(t *testing.T) {
+	assert.True(t, AtLeast("1.22", 1, 22))
+	assert.True(t, AtLeast("1.22.3", 1, 22))
+	assert.True(t, AtLeast("1.23rc1", 1, 22))
+	assert.True(t, AtLeast("2.0", 1, 22))
+	assert.False(t, AtLeast("1.21", 1, 22))
+	assert.False(t, AtLeast("1.21.9", 1, 22))
+	assert.False(t, AtLeast("", 1, 22))
+	assert.False(t, AtLeast("garbage", 1, 22))
+}
+
+func TestDirFor(t *testing.T) {
+	mod := &Module{Dir: "repo", Path: "github.com/owner/repo"}
+	dir, ok := mod.DirFor("github.com/owner/repo/pkg/a")
+	assert.True(t, ok)
+	assert.Equal(t, "repo/pkg/a", dir)
+	dir, ok = mod.DirFor("github.com/owner/repo")
+	assert.True(t, ok)
+	assert.Equal(t, "repo", dir)
+	_, ok = mod.DirFor("github.com/owner/repository")
+	assert.False(t, ok)
+	_, ok = (*Module)(nil).DirFor("github.com/owner/repo")
+	assert.False(t, ok)
+}
+
+func TestNestedModules(t *testing.T) {
+	goMods := map[string][]byte{
+		"go.mod":        []byte("module example.com/root\ngo 1.16\n"),
+		"nested/go.mod": []byte("module example.com/nested\ngo 1.22\n"),
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range []string{"main.go", "pkg/pkg.go", "nested/main.go", "nested/deep/deep.go"} {
+		file, err := parser.ParseFile(fset, name, "package p", 0)
+		assert.NoError(t, err)
+		files = append(files, file)
+	}
+	result := Analyze(fset, files, Files(goMods))
+
+	var paths []string
+	var perIteration []bool
+	for _, file := range files {
+		mod := result.ModuleFor(file.Pos())
+		paths = append(paths, mod.Path)
+		perIteration = append(perIteration, mod.PerIterationLoopVars())
+	}
+	assert.Equal(t, []string{"example.com/root", "example.com/root", "example.com/nested", "example.com/nested"}, paths)
+	assert.Equal(t, []bool{false, false, true, true}, perIteration)
+}
+
+func TestNoReadFile(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", "package p", 0)
+	assert.NoError(t, err)
+	res, err := run(&analysis.Pass{Fset: fset, Files: []*ast.File{file}})
+	assert.NoError(t, err)
+	assert.Nil(t, res.(*Result).ModuleFor(file.Pos()))
+	assert.False(t, res.(*Result).ModuleFor(file.Pos()).PerIterationLoopVars())
+}
@@ -8,35 +8,46 @@ import (
 	"strings"
 	"text/template"
 
+	"github.com/github-vet/bots/cmd/vet-bot/classify"
 	"github.com/github-vet/bots/internal/db"
+	"github.com/github-vet/bots/internal/snippet"
 	"github.com/google/go-github/v32/github"
 )
 
 // Md5Checksum represents an MD5 checksum as per the standard library.
 type Md5Checksum [md5.Size]byte
 
-// IssueReporter reports issues and maintains an in-memory store of reported code snippets to prevent
-// exact duplicates from being reported.
+// IssueReporter passes findings along to a FindingSink and maintains an in-memory store of reported code snippets
+// to prevent exact duplicates from being reported. Near-duplicates are passed along, after being linked to the
+// finding they duplicate, if the sink recorded that finding in the database.
 type IssueReporter struct {
-	bot   *VetBot
-	md5s  map[Md5Checksum]struct{} // hashes of the code reported to protect against vendored / duplicated code
-	owner string
-	repo  string
+	bot          *VetBot
+	md5s         map[Md5Checksum]struct{} // hashes of the code reported to protect against vendored / duplicated code
+	fingerprints map[string]int64         // the ID of the original finding for each fingerprint reported; 0 if it was not recorded
+	sink         FindingSink
 }
 
-// NewIssueReporter constructs a new issue reporter with the provided bot. The issue file will be
-// created if it doesn't already exist. It stores a list of issues which have already been opened.
-func NewIssueReporter(bot *VetBot, owner, repo string) (*IssueReporter, error) {
+// NewIssueReporter constructs a new issue reporter with the provided bot, which passes each new finding to
+// the provided sink. The checksums of findings which were already reported are read from the database.
+func NewIssueReporter(bot *VetBot, sink FindingSink) (*IssueReporter, error) {
 	md5s, err := readMd5sFromDB(bot)
 	if err != nil {
 		return nil, err
 	}
+	err = backfillFingerprints(bot)
+	if err != nil {
+		return nil, err
+	}
+	fingerprints, err := readOriginalsFromDB(bot)
+	if err != nil {
+		return nil, err
+	}
 
 	return &IssueReporter{
-		bot:   bot,
-		md5s:  md5s,
-		owner: owner,
-		repo:  repo,
+		bot:          bot,
+		md5s:         md5s,
+		fingerprints: fingerprints,
+		sink:         sink,
 	}, nil
 }
 
@@ -55,7 +66,49 @@ func readMd5sFromDB(bot *VetBot) (map[Md5Checksum]struct{}, error) {
 	return result, nil
 }
 
-// ReportVetResult asynchronously creates a new GitHub issue to report the findings of the VetResult.
+// backfillFingerprints fingerprints the findings which were recorded before fingerprints were introduced.
+func backfillFingerprints(bot *VetBot) error {
+	ctx := context.Background()
+	findings, err := db.FindingDAO.ListUnfingerprinted(ctx, bot.db)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, finding := range findings {
+		fingerprint := snippet.Fingerprint(finding.Quote)
+		if fingerprint == nil {
+			continue
+		}
+		if _, err := db.FindingDAO.SetFingerprint(ctx, bot.db, finding.ID, fingerprint); err != nil {
+			return fmt.Errorf("cannot fingerprint finding %d: %w", finding.ID, err)
+		}
+		count++
+	}
+	if count > 0 {
+		log.Printf("fingerprinted %d existing findings", count)
+	}
+	return nil
+}
+
+// readOriginalsFromDB maps the fingerprint of each original finding in the database to its ID. If several original
+// findings share a fingerprint, the earliest is kept.
+func readOriginalsFromDB(bot *VetBot) (map[string]int64, error) {
+	originals, err := db.FindingDAO.ListOriginals(context.Background(), bot.db)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]int64, len(originals))
+	for _, original := range originals {
+		if _, ok := result[string(original.Fingerprint)]; !ok {
+			result[string(original.Fingerprint)] = original.ID
+		}
+	}
+	return result, nil
+}
+
+// ReportVetResult passes the VetResult along to the sink, unless the same code has already been reported. If
+// code with the same fingerprint was reported, the result is linked to the original finding before it is passed on,
+// provided the sink recorded the original finding in the database.
 func (ir *IssueReporter) ReportVetResult(result VetResult) {
 	md5Sum := md5.Sum([]byte(result.Quote))
 	if _, ok := ir.md5s[md5Sum]; ok {
@@ -64,72 +117,32 @@ func (ir *IssueReporter) ReportVetResult(result VetResult) {
 	}
 	ir.md5s[md5Sum] = struct{}{}
 
-	// TODO: we can't make this non-blocking until proteus can return an sql.Result
-	//       async usage here causes a race-condition in persistResult with last_insert_rowid()
-	/*ir.bot.wg.Add(1)
-	go func(result VetResult) {*/
-	var (
-		iss *github.Issue
-		err error
-	)
-	if shouldReportToGithub(result.FilePath) {
-		issueRequest := CreateIssueRequest(result)
-		iss, _, err = ir.bot.client.CreateIssue(ir.owner, ir.repo, &issueRequest)
-		if err != nil {
-			log.Printf("error opening new issue: %v", err)
-			return
+	result.Fingerprint = snippet.Fingerprint(result.Quote)
+	original, isDuplicate := ir.fingerprints[string(result.Fingerprint)]
+	if result.Fingerprint != nil && isDuplicate {
+		result.DuplicateOf = original
+		if original != 0 {
+			log.Printf("found near-duplicate of finding %d in %s", original, result.FilePath)
+		} else {
+			log.Printf("found near-duplicate of an unrecorded finding in %s", result.FilePath)
 		}
 	}
 
-	ir.persistResult(result, iss, md5Sum)
-	log.Printf("opened new issue at %s", iss.GetHTMLURL())
-	/*ir.bot.wg.Done()
-	}(result)*/
-}
-
-func shouldReportToGithub(filepath string) bool {
-	if strings.HasSuffix(filepath, "_test.go") || strings.HasPrefix(filepath, "vendor/") {
-		return false
-	}
-	return true
-}
-
-// persistResult writes the provided VetResult and github.Issue to the database (if the issue is non-nil).
-// It is not thread-safe (yet).
-func (ir *IssueReporter) persistResult(result VetResult, issue *github.Issue, md5Sum [16]byte) error {
-	_, err := db.FindingDAO.Create(context.Background(), ir.bot.db, db.Finding{
-		GithubOwner:  result.Owner,
-		GithubRepo:   result.Repo,
-		Filepath:     result.FilePath,
-		RootCommitID: result.RootCommitID,
-		Quote:        result.Quote,
-		QuoteMD5Sum:  db.Md5Sum(md5Sum[:]),
-		StartLine:    result.Start.Line,
-		EndLine:      result.End.Line,
-		Message:      result.Message,
-		ExtraInfo:    result.ExtraInfo,
-	})
+	// TODO: we can't make this non-blocking until proteus can return an sql.Result
+	//       async usage here causes a race-condition in persistResult with last_insert_rowid()
+	id, err := ir.sink.Consume(result, md5Sum)
 	if err != nil {
-		return fmt.Errorf("error persisting finding: %w", err)
+		log.Printf("error reporting finding in %s: %v", result.FilePath, err)
+		return
 	}
-	findingID, err := db.LastInsertID(ir.bot.db) // TODO: not this;
-	if err != nil {
-		return fmt.Errorf("error retrieving finding ID: %w", err)
+	if result.Fingerprint != nil && !isDuplicate {
+		ir.fingerprints[string(result.Fingerprint)] = id
 	}
+}
 
-	if issue == nil {
-		return nil
-	}
-	_, err = db.IssueDAO.Upsert(context.Background(), ir.bot.db, db.Issue{
-		FindingID:   findingID,
-		GithubOwner: ir.owner,
-		GithubRepo:  ir.repo,
-		GithubID:    issue.GetNumber(),
-	})
-	if err != nil {
-		return fmt.Errorf("error persisting issue: %w", err)
-	}
-	return nil
+// FinishRepository notifies the sink that every finding in the provided repository has been reported.
+func (ir *IssueReporter) FinishRepository(repo Repository) error {
+	return ir.sink.FinishRepository(repo)
 }
 
 // CreateIssueRequest writes the header and description of the GitHub issue which is opened with the result
@@ -183,18 +196,21 @@ func Labels(result VetResult) []string {
 	} else {
 		labels = append(labels, "huge")
 	}
-	if strings.HasSuffix(result.FilePath, "_test.go") {
-		labels = append(labels, "test")
-	}
-	if strings.HasPrefix(result.FilePath, "vendor/") {
-		labels = append(labels, "vendored")
+	if result.Class != classify.ClassNormal {
+		labels = append(labels, result.Class.String()) // e.g. "test", "vendored" or "generated"
 	}
 	return labels
 }
 
+// DuplicateComment writes the comment left on the issue of an original finding when a near-duplicate is found.
+func DuplicateComment(result VetResult) string {
+	return fmt.Sprintf("Found a near-duplicate of this snippet in [%s/%s](https://www.github.com/%s/%s) at [%s](%s).\n\n> %s\n",
+		result.Owner, result.Repo, result.Owner, result.Repo, result.FilePath, result.Permalink(), result.Message)
+}
+
 // State returns the desired status of a VetResult on issue creation.
 func State(result VetResult) string {
-	if strings.HasSuffix(result.FilePath, "_test.go") {
+	if result.Class == classify.ClassTest {
 		return "closed"
 	}
 	return "open"
@@ -213,10 +229,14 @@ type IssueResult struct {
 var IssueResultTemplate string = `
 Found a possible issue in [{{.Repository.Owner}}/{{.Repository.Repo}}](https://www.github.com/{{.Repository.Owner}}/{{.Repository.Repo}}) at [{{.FilePath}}]({{.Link}})
 
-Below is the message reported by the analyzer for this snippet of code. Beware that the analyzer only reports the first issue it finds, so please do not limit your consideration to the contents of the below message.
+Below is the message reported by the analyzer for this snippet of code.
 
 > {{.Message}}
-
+{{if gt (len .SubFindings) 1}}
+The analyzer found {{len .SubFindings}} issues in this snippet of code.
+{{range .SubFindings}}
+* {{.Message}}{{end}}
+{{end}}
 [Click here to see the code in its original context.]({{.Link}})
 
 <details>
@@ -227,6 +247,14 @@ Below is the message reported by the analyzer for this snippet of code. Beware t
 ~~~
 </details>
 
+{{if .SuggestedFix}}
+<details>
+<summary>Click here to show a fix suggested by the analyzer.</summary>
+
+~~~diff
+{{.SuggestedFix}}~~~
+</details>
+{{end}}
 {{if .ExtraInfo}}
 <details>
 <summary>Click here to show extra information the analyzer produced.</summary>
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/github-vet/bots/cmd/vet-bot/classify"
+	"github.com/github-vet/bots/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	originalQuote = `for _, item := range items {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		process(&item, 42)
+		log.Printf("processed %v", item)
+	}()
+}`
+	renamedQuote = `for _, x := range items {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		process(&x, 42)
+		log.Printf("processed %v", x)
+	}()
+}`
+)
+
+// recordingSink records the results it consumes before passing them along to another sink.
+type recordingSink struct {
+	FindingSink
+	results []VetResult
+}
+
+func (rs *recordingSink) Consume(result VetResult, md5Sum Md5Checksum) (int64, error) {
+	rs.results = append(rs.results, result)
+	return rs.FindingSink.Consume(result, md5Sum)
+}
+
+func newTestBot(t *testing.T) *VetBot {
+	DB, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { DB.Close() })
+	require.NoError(t, db.BootstrapDB("../../internal/db/bootstrap", DB))
+	return &VetBot{db: DB, classifier: classify.Default()}
+}
+
+func TestIssueReporterNearDuplicates(t *testing.T) {
+	bot := newTestBot(t)
+	DB := bot.db
+	result := VetResult{Repository: Repository{Owner: "owner", Repo: "repo"}, FilePath: "foo.go", Quote: originalQuote}
+
+	// a finding recorded before fingerprints were introduced.
+	originalID, err := persistResult(bot, result, nil, "", "", md5.Sum([]byte(originalQuote)))
+	require.NoError(t, err)
+
+	sink := &recordingSink{FindingSink: &DatabaseSink{bot: bot}}
+	reporter, err := NewIssueReporter(bot, sink)
+	require.NoError(t, err)
+
+	reporter.ReportVetResult(result)
+	assert.Empty(t, sink.results, "exact duplicates should not be reported")
+
+	result.Quote = renamedQuote
+	reporter.ReportVetResult(result)
+	require.Len(t, sink.results, 1)
+	assert.NotNil(t, sink.results[0].Fingerprint)
+	assert.EqualValues(t, originalID, sink.results[0].DuplicateOf)
+
+	ctx := context.Background()
+	original, err := db.FindingDAO.FindByID(ctx, DB, originalID)
+	require.NoError(t, err)
+	duplicate, err := db.FindingDAO.FindByID(ctx, DB, originalID+1)
+	require.NoError(t, err)
+	assert.Equal(t, sink.results[0].Fingerprint, []byte(original.Fingerprint), "existing findings should be fingerprinted")
+	assert.Equal(t, original.Fingerprint, duplicate.Fingerprint)
+	assert.EqualValues(t, originalID, duplicate.DuplicateOf)
+}
+
+func TestIssueReporterShortRenamedLoop(t *testing.T) {
+	bot := newTestBot(t)
+	sink := &recordingSink{FindingSink: &DatabaseSink{bot: bot}}
+	reporter, err := NewIssueReporter(bot, sink)
+	require.NoError(t, err)
+
+	result := VetResult{Repository: Repository{Owner: "owner", Repo: "repo"}, FilePath: "foo.go"}
+	result.Quote = "for _, x := range xs {\n\tgo func() {\n\t\tuse(x)\n\t}()\n}\n"
+	reporter.ReportVetResult(result)
+	result.Quote = "for _, v := range xs {\n\tgo func() {\n\t\tuse(v)\n\t}()\n}\n"
+	reporter.ReportVetResult(result)
+
+	require.Len(t, sink.results, 2)
+	assert.NotNil(t, sink.results[0].Fingerprint)
+	assert.Zero(t, sink.results[0].DuplicateOf)
+	assert.Equal(t, sink.results[0].Fingerprint, sink.results[1].Fingerprint)
+	assert.EqualValues(t, 1, sink.results[1].DuplicateOf)
+}
+
+func TestIssueReporterNearDuplicatesUnrecorded(t *testing.T) {
+	bot := newTestBot(t)
+	var buf bytes.Buffer
+	sink := &recordingSink{FindingSink: NewJSONLinesSink(&buf)}
+	reporter, err := NewIssueReporter(bot, sink)
+	require.NoError(t, err)
+
+	result := VetResult{Repository: Repository{Owner: "owner", Repo: "repo"}, FilePath: "foo.go", Quote: originalQuote}
+	reporter.ReportVetResult(result)
+	result.Quote = renamedQuote
+	reporter.ReportVetResult(result)
+
+	require.Len(t, sink.results, 2)
+	assert.Equal(t, sink.results[0].Fingerprint, sink.results[1].Fingerprint)
+	assert.Zero(t, sink.results[1].DuplicateOf, "findings which were not recorded cannot be linked")
+}
@@ -8,7 +8,17 @@ package loopclosure
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
 
+	"github.com/github-vet/bots/cmd/vet-bot/acceptlist"
+	"github.com/github-vet/bots/cmd/vet-bot/callgraph"
+	"github.com/github-vet/bots/cmd/vet-bot/fixes"
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
+	"github.com/github-vet/bots/cmd/vet-bot/nogofunc"
+	"github.com/github-vet/bots/cmd/vet-bot/packid"
 	"github.com/github-vet/bots/cmd/vet-bot/stats"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
@@ -22,9 +32,9 @@ run the type-checker, which is a net win.`
 
 // Analyzer provides the loopclosure analyzer.
 var Analyzer = &analysis.Analyzer{
-	Name:     "loopclosure-augmented",
+	Name:     "loopclosure_augmented",
 	Doc:      doc,
-	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Requires: []*analysis.Analyzer{inspect.Analyzer, gomod.Analyzer, packid.Analyzer, callgraph.Analyzer, nogofunc.Analyzer},
 	Run:      run,
 }
 
@@ -33,24 +43,55 @@ func run(pass *analysis.Pass) (interface{}, error) {
 
 	nodeFilter := []ast.Node{
 		(*ast.RangeStmt)(nil),
+		(*ast.ForStmt)(nil),
 	}
-	inspect.Preorder(nodeFilter, func(n ast.Node) {
-		inspectBody(n, nil, pass)
+	c := &checker{
+		pass:     pass,
+		packages: pass.ResultOf[packid.Analyzer].(*packid.PackageResolver),
+		graph:    pass.ResultOf[callgraph.Analyzer].(*callgraph.Result),
+		async:    pass.ResultOf[nogofunc.Analyzer].(*nogofunc.Result),
+		reported: make(map[*ast.Ident]bool),
+		passed:   make(map[*ast.FuncLit]bool),
+		copied:   make(map[*ast.Object]bool),
+	}
+	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if push {
+			c.file = stack[:1]
+			c.inspectBody(n, nil)
+		}
+		return true
 	})
 	return nil, nil
 }
 
+// checker holds the state shared while inspecting each loop.
+type checker struct {
+	pass     *analysis.Pass
+	packages *packid.PackageResolver
+	graph    *callgraph.Result
+	async    *nogofunc.Result
+	file     []ast.Node // a stack containing only the file being inspected, used to resolve package names
+	// nested loops are visited both on their own and while inspecting their enclosing loop, so each use of a loop
+	// variable is only reported the first time it is found.
+	reported map[*ast.Ident]bool
+	// fixes are only suggested once for each function literal, and once for each loop variable copied, since every
+	// use of a loop variable is reported separately.
+	passed map[*ast.FuncLit]bool
+	copied map[*ast.Object]bool
+}
+
 type loopVar struct {
 	ident *ast.Ident
-	body  *ast.RangeStmt
+	body  ast.Stmt // either a *ast.RangeStmt or a *ast.ForStmt
 }
 
-func inspectBody(n ast.Node, outerVars []loopVar, pass *analysis.Pass) {
+func (c *checker) inspectBody(n ast.Node, outerVars []loopVar) {
+	pass := c.pass
 	loopVars := make([]loopVar, len(outerVars))
 	copy(loopVars, outerVars)
 
 	// Find the variables updated by the loop statement.
-	addVar := func(expr ast.Expr, body *ast.RangeStmt) {
+	addVar := func(expr ast.Expr, body ast.Stmt) {
 		if id, ok := expr.(*ast.Ident); ok {
 			loopVars = append(loopVars, loopVar{
 				ident: id,
@@ -64,9 +105,15 @@ func inspectBody(n ast.Node, outerVars []loopVar, pass *analysis.Pass) {
 		body = n.Body
 		addVar(n.Key, n)
 		addVar(n.Value, n)
-	// Keep checking the contents of nested blocks, but only capture range loop variables as targets
 	case *ast.ForStmt:
 		body = n.Body
+		// only variables declared in the init statement are shared between iterations.
+		if init, ok := n.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+			for _, lhs := range init.Lhs {
+				addVar(lhs, n)
+			}
+		}
+	// Keep checking the contents of nested blocks, but only capture loop variables as targets
 	case *ast.IfStmt:
 		body = n.Body
 	case *ast.SwitchStmt:
@@ -76,7 +123,9 @@ func inspectBody(n ast.Node, outerVars []loopVar, pass *analysis.Pass) {
 		return
 	}
 
-	inspectFuncLit := func(lit *ast.FuncLit) {
+	modules := pass.ResultOf[gomod.Analyzer].(*gomod.Result)
+	// inspectFuncLit reports each loop variable used in the provided function literal.
+	inspectFuncLit := func(lit *ast.FuncLit, use asyncUse) {
 		ast.Inspect(lit.Body, func(n ast.Node) bool {
 			id, ok := n.(*ast.Ident)
 			if !ok || id.Obj == nil {
@@ -87,21 +136,59 @@ func inspectBody(n ast.Node, outerVars []loopVar, pass *analysis.Pass) {
 				return true
 			}
 			for _, v := range loopVars {
-				if v.ident.Obj == id.Obj {
+				if v.ident.Obj == id.Obj && !c.reported[id] {
+					c.reported[id] = true
+					if modules.ModuleFor(v.body.Pos()).PerIterationLoopVars() {
+						// since Go 1.22, each iteration of the loop has its own copy of the variable.
+						stats.AddCount(stats.StatLoopclosureSuppressedGo122, 1)
+						continue
+					}
 					stats.AddCount(stats.StatLoopclosureHits, 1)
+					kind := "range-loop"
+					if _, ok := v.body.(*ast.ForStmt); ok {
+						stats.AddCount(stats.StatLoopclosureForLoopHits, 1)
+						kind = "for-loop"
+					}
+					related := []analysis.RelatedInformation{
+						{Message: pass.Fset.File(v.body.Pos()).Name()},
+					}
+					if use.explanation != "" {
+						related = append(related, analysis.RelatedInformation{Message: use.explanation})
+					}
 					pass.Report(analysis.Diagnostic{
-						Pos:     v.body.Pos(),
-						End:     v.body.End(),
-						Message: fmt.Sprintf("range-loop variable %s used in defer or goroutine at line %d", id.Name, pass.Fset.Position(id.Pos()).Line),
-						Related: []analysis.RelatedInformation{
-							{Message: pass.Fset.File(v.body.Pos()).Name()},
-						},
+						Pos:            v.body.Pos(),
+						End:            v.body.End(),
+						Message:        use.message(kind, id.Name, pass.Fset.Position(id.Pos()).Line),
+						Related:        related,
+						SuggestedFixes: c.suggestFix(v, lit, use, loopVars),
 					})
 				}
 			}
 			return true
 		})
 	}
+	// inspectCall checks every function literal passed to calls which may run them asynchronously.
+	inspectCall := func(expr ast.Expr) {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return
+		}
+		for idx, arg := range call.Args {
+			lit, ok := arg.(*ast.FuncLit)
+			if !ok {
+				continue
+			}
+			use, ok := c.asyncUseOf(call, idx)
+			if !ok {
+				continue
+			}
+			// subtests only run asynchronously once they call t.Parallel.
+			if use.callee == "testing.T.Run" && !callsParallel(lit) {
+				continue
+			}
+			inspectFuncLit(lit, use)
+		}
+	}
 
 	if body == nil || len(body.List) == 0 {
 		return
@@ -110,22 +197,229 @@ func inspectBody(n ast.Node, outerVars []loopVar, pass *analysis.Pass) {
 		switch s := stmt.(type) {
 		case *ast.GoStmt:
 			if lit, ok := s.Call.Fun.(*ast.FuncLit); ok {
-				inspectFuncLit(lit)
+				inspectFuncLit(lit, asyncUse{call: s.Call})
 			}
 		case *ast.DeferStmt:
 			if lit, ok := s.Call.Fun.(*ast.FuncLit); ok {
-				inspectFuncLit(lit)
+				inspectFuncLit(lit, asyncUse{call: s.Call})
+			}
+		case *ast.ExprStmt:
+			inspectCall(s.X)
+		case *ast.AssignStmt:
+			for _, rhs := range s.Rhs {
+				inspectCall(rhs)
 			}
 
 		// recurse into nested loops as well and perform the same check.
 		case *ast.RangeStmt:
-			inspectBody(s, loopVars, pass)
+			c.inspectBody(s, loopVars)
 		case *ast.ForStmt:
-			inspectBody(s, loopVars, pass)
+			c.inspectBody(s, loopVars)
 		case *ast.IfStmt:
-			inspectBody(s, loopVars, pass)
+			c.inspectBody(s, loopVars)
 		case *ast.SwitchStmt:
-			inspectBody(s, loopVars, pass)
+			c.inspectBody(s, loopVars)
+		}
+	}
+}
+
+// asyncUse describes how a function literal may be run after the current iteration of a loop has finished.
+type asyncUse struct {
+	// callee is the name of the function the literal is passed to; it is empty for go and defer statements.
+	callee string
+	// explanation describes paths through the callgraph by which a function declared in the repository may start
+	// a goroutine. It is empty for third-party functions from the accept list.
+	explanation string
+	// call is the call to the function literal made by a go or defer statement; it is nil otherwise.
+	call *ast.CallExpr
+}
+
+func (u asyncUse) message(kind, name string, line int) string {
+	switch {
+	case u.callee == "":
+		return fmt.Sprintf("%s variable %s used in defer or goroutine at line %d", kind, name, line)
+	case u.explanation == "":
+		return fmt.Sprintf("%s variable %s used in function passed to %s at line %d", kind, name, u.callee, line)
+	default:
+		return fmt.Sprintf("%s variable %s used in closure passed to %s at line %d, which may run it asynchronously", kind, name, u.callee, line)
+	}
+}
+
+// suggestFix suggests a fix for a use of the provided loop variable within a function literal. Literals started by go
+// or defer statements are passed every loop variable they use as an argument, provided the type of each variable is
+// known. Otherwise, the variable is copied at the top of the body of its loop.
+func (c *checker) suggestFix(v loopVar, lit *ast.FuncLit, use asyncUse, loopVars []loopVar) []analysis.SuggestedFix {
+	if use.call != nil {
+		if c.passed[lit] {
+			return nil
+		}
+		if edits, ok := c.passAsArguments(lit, use.call, loopVars); ok {
+			c.passed[lit] = true
+			return []analysis.SuggestedFix{{Message: "pass loop variables as arguments", TextEdits: edits}}
+		}
+	}
+	if c.copied[v.ident.Obj] {
+		return nil
+	}
+	var body *ast.BlockStmt
+	switch loop := v.body.(type) {
+	case *ast.RangeStmt:
+		body = loop.Body
+	case *ast.ForStmt:
+		// the variables of a three-clause for loop can't be copied if the body of the loop updates them.
+		if fixes.Assigns(loop.Body, v.ident.Obj) {
+			return nil
+		}
+		body = loop.Body
+	}
+	if body == nil || len(body.List) == 0 {
+		return nil
+	}
+	c.copied[v.ident.Obj] = true
+	return []analysis.SuggestedFix{{
+		Message:   "copy loop variables",
+		TextEdits: []analysis.TextEdit{fixes.CopyVars(c.pass.Fset, body.List[0].Pos(), []string{v.ident.Name})},
+	}}
+}
+
+// passAsArguments returns edits which add a parameter to the provided function literal for each loop variable it
+// uses, and pass the variable as an argument to the call of the literal. ok is false if the type of any variable is
+// unknown, or can't be named from the file being inspected.
+func (c *checker) passAsArguments(lit *ast.FuncLit, call *ast.CallExpr, loopVars []loopVar) (edits []analysis.TextEdit, ok bool) {
+	info := c.pass.TypesInfo
+	params := lit.Type.Params.List
+	if info == nil || (len(params) > 0 && isVariadic(params[len(params)-1])) {
+		return nil, false
+	}
+	var used []loopVar
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Obj == nil {
+			return true
+		}
+		for _, v := range loopVars {
+			if v.ident.Obj == id.Obj && !containsVar(used, v) {
+				used = append(used, v)
+			}
+		}
+		return true
+	})
+	var decls, names []string
+	for _, v := range used {
+		obj := info.Defs[v.ident]
+		if obj == nil {
+			return nil, false
+		}
+		typ, ok := typeString(obj, c.file[0].(*ast.File))
+		if !ok {
+			return nil, false
+		}
+		decls = append(decls, v.ident.Name+" "+typ)
+		names = append(names, v.ident.Name)
+	}
+	if len(used) == 0 {
+		return nil, false
+	}
+	paramPos, paramSep := lit.Type.Params.Closing, ""
+	if len(params) > 0 {
+		paramPos, paramSep = params[len(params)-1].End(), ", "
+	}
+	argPos, argSep := call.Rparen, ""
+	if len(call.Args) > 0 {
+		argPos, argSep = call.Args[len(call.Args)-1].End(), ", "
+	}
+	return []analysis.TextEdit{
+		{Pos: paramPos, End: paramPos, NewText: []byte(paramSep + strings.Join(decls, ", "))},
+		{Pos: argPos, End: argPos, NewText: []byte(argSep + strings.Join(names, ", "))},
+	}, true
+}
+
+func isVariadic(field *ast.Field) bool {
+	_, ok := field.Type.(*ast.Ellipsis)
+	return ok
+}
+
+func containsVar(vars []loopVar, v loopVar) bool {
+	for _, other := range vars {
+		if other.ident.Obj == v.ident.Obj {
+			return true
+		}
+	}
+	return false
+}
+
+// typeString returns the type of the provided object, as written in the provided file. ok is false if the type
+// refers to a package which is not imported by the file.
+func typeString(obj types.Object, file *ast.File) (string, bool) {
+	names := make(map[string]string) // import paths to the names they are imported under
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		names[path] = ""
+		if spec.Name != nil {
+			names[path] = spec.Name.Name
 		}
 	}
+	ok := true
+	typ := types.TypeString(obj.Type(), func(pkg *types.Package) string {
+		if pkg == obj.Pkg() {
+			return ""
+		}
+		name, imported := names[pkg.Path()]
+		switch {
+		case !imported || name == "_":
+			ok = false
+		case name == "":
+			return pkg.Name()
+		case name == ".":
+			return ""
+		}
+		return name
+	})
+	return typ, ok
+}
+
+// asyncUseOf returns true if the provided call may run the function literal passed as its argument at the provided
+// index asynchronously; either because it is listed in the accept list, or because the argument may reach a
+// goroutine through the callgraph.
+func (c *checker) asyncUseOf(call *ast.CallExpr, idx int) (asyncUse, bool) {
+	if sink, ok := acceptlist.AsyncCall(c.packages, call, c.file); ok {
+		return asyncUse{callee: sink}, true
+	}
+	sig := c.graph.CallSignature(call)
+	if !containsIndex(c.async.AsyncFuncArgs[sig], idx) {
+		return asyncUse{}, false
+	}
+	paths := make(callgraph.PathGraph)
+	c.async.FuncArgGraph.BFSWithStack(sig, func(sig callgraph.Signature, stack []callgraph.Signature) {
+		if _, ok := c.async.StartsFuncArg[sig]; ok {
+			paths.AddPath(stack)
+		}
+	})
+	return asyncUse{callee: sig.Name, explanation: paths.Report("function calling a goroutine")}, true
+}
+
+func containsIndex(arr []int, idx int) bool {
+	for _, x := range arr {
+		if x == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// callsParallel returns true if the provided function literal calls a method named Parallel, as in t.Parallel().
+func callsParallel(lit *ast.FuncLit) bool {
+	found := false
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && len(call.Args) == 0 {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Parallel" {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
 }
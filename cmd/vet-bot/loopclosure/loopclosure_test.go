@@ -1,10 +1,21 @@
 package loopclosure_test
 
 import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
 	"testing"
 
+	"github.com/github-vet/bots/cmd/vet-bot/acceptlist"
+	"github.com/github-vet/bots/cmd/vet-bot/driver"
+	"github.com/github-vet/bots/cmd/vet-bot/driver/drivertest"
 	"github.com/github-vet/bots/cmd/vet-bot/loopclosure"
+	"github.com/github-vet/bots/cmd/vet-bot/stats"
+	"github.com/stretchr/testify/assert"
 
+	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/analysistest"
 )
 
@@ -12,3 +23,81 @@ func Test(t *testing.T) {
 	testdata := analysistest.TestData()
 	analysistest.Run(t, testdata, loopclosure.Analyzer, "safe-usage")
 }
+
+func TestForLoop(t *testing.T) {
+	stats.Clear()
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, loopclosure.Analyzer, "forloop")
+	assert.EqualValues(t, 2, stats.GetCount(stats.StatLoopclosureHits))
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatLoopclosureForLoopHits))
+}
+
+func TestAsyncSinks(t *testing.T) {
+	acceptlist.SetGlobalAcceptList(&acceptlist.AcceptList{
+		Async: map[string]map[string]struct{}{
+			"golang.org/x/sync/errgroup":   {"Group.Go": {}},
+			"github.com/panjf2000/ants/v2": {"Pool.Submit": {}},
+			"testing":                      {"T.Run": {}},
+		},
+	})
+	defer func() { acceptlist.SetGlobalAcceptList(nil) }()
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, loopclosure.Analyzer, "asyncsinks")
+
+	// without type information, receivers are resolved from the declarations of the variables they name.
+	reported := drivertest.Run(t, filepath.Join(testdata, "src", "asyncsinks"), loopclosure.Analyzer)
+	assert.Equal(t, []string{
+		"range-loop variable x used in function passed to golang.org/x/sync/errgroup.Group.Go at line 15",
+		"for-loop variable i used in function passed to golang.org/x/sync/errgroup.Group.Go at line 24",
+		"range-loop variable x used in function passed to github.com/panjf2000/ants/v2.Pool.Submit at line 35",
+		"range-loop variable x used in function passed to testing.T.Run at line 49",
+	}, reported)
+}
+
+func TestLocalAsync(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, loopclosure.Analyzer, "localasync")
+	for _, result := range results {
+		for _, d := range result.Diagnostics {
+			if assert.Len(t, d.Related, 2) {
+				assert.Contains(t, d.Related[1].Message, `"(localasync.runAsync, 1)"`)
+			}
+		}
+	}
+}
+
+func TestGo122(t *testing.T) {
+	stats.Clear()
+	reported := drivertest.Run(t, filepath.Join(analysistest.TestData(), "src", "go122"), loopclosure.Analyzer)
+	assert.Empty(t, reported)
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatLoopclosureSuppressedGo122))
+	assert.EqualValues(t, 0, stats.GetCount(stats.StatLoopclosureHits))
+}
+
+func TestSuggestedFixes(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, loopclosure.Analyzer, "fixes")
+}
+
+func TestSuggestedFixesUntyped(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(analysistest.TestData(), "src", "fixes", "fixes.go"), nil, 0)
+	assert.NoError(t, err)
+	var fixed []string
+	_, err = driver.Run(fset, []*ast.File{file}, nil, []*analysis.Analyzer{loopclosure.Analyzer}, func(d analysis.Diagnostic) {
+		for _, fix := range d.SuggestedFixes {
+			for _, edit := range fix.TextEdits {
+				fixed = append(fixed, fmt.Sprintf("%s: %d: %q", fix.Message, fset.Position(edit.Pos).Line, edit.NewText))
+			}
+		}
+	})
+	assert.NoError(t, err)
+	// without type information, loop variables can only be copied.
+	assert.Equal(t, []string{
+		"copy loop variables: 11: \"i := i\\n\\t\\t\"",
+		"copy loop variables: 11: \"it := it\\n\\t\\t\"",
+		"copy loop variables: 19: \"d := d\\n\\t\\t\"",
+		"copy loop variables: 27: \"j := j\\n\\t\\t\"",
+		"copy loop variables: 39: \"v := v\\n\\t\\t\"",
+	}, fixed)
+}
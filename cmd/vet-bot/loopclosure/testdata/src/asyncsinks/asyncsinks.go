@@ -0,0 +1,71 @@
+package asyncsinks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/panjf2000/ants/v2"
+	"golang.org/x/sync/errgroup"
+)
+
+func errgroups(ctx context.Context, xs []int) error {
+	var g errgroup.Group
+	for _, x := range xs { // want `range-loop variable x used in function passed to golang.org/x/sync/errgroup.Group.Go at line 15`
+		g.Go(func() error {
+			println(x)
+			return nil
+		})
+	}
+
+	g2, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < len(xs); i++ { // want `for-loop variable i used in function passed to golang.org/x/sync/errgroup.Group.Go at line 24`
+		g2.Go(func() error {
+			<-ctx.Done()
+			println(i)
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+func pools(xs []int) {
+	pool, _ := ants.NewPool(10)
+	for _, x := range xs { // want `range-loop variable x used in function passed to github.com/panjf2000/ants/v2.Pool.Submit at line 35`
+		_ = pool.Submit(func() {
+			println(x)
+		})
+	}
+}
+
+func TestSubtests(t *testing.T) {
+	for _, x := range []int{1, 2} {
+		t.Run("sequential", func(t *testing.T) {
+			println(x) // safe, since the subtest completes before the loop continues
+		})
+	}
+	for _, x := range []int{1, 2} { // want `range-loop variable x used in function passed to testing.T.Run at line 49`
+		t.Run("parallel", func(t *testing.T) {
+			t.Parallel()
+			println(x)
+		})
+	}
+}
+
+func notAsync(xs []int) {
+	var notAPool struct{ Submit func(func()) }
+	for _, x := range xs {
+		notAPool.Submit(func() {
+			println(x)
+		})
+	}
+}
+
+func otherGo(xs []int) {
+	var r errgroup.Runner
+	for _, x := range xs {
+		r.Go(func() error {
+			println(x)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package fixes
+
+import "time"
+
+type item struct {
+	id int
+}
+
+func goroutines(items []item) {
+	for i, it := range items { // want `range-loop variable i used in defer or goroutine at line 12` `range-loop variable it used in defer or goroutine at line 12`
+		go func() {
+			println(i, it.id)
+		}()
+	}
+}
+
+func existingParams(delays []time.Duration) {
+	for _, d := range delays { // want `range-loop variable d used in defer or goroutine at line 20`
+		go func(n int) {
+			println(n, d)
+		}(1)
+	}
+}
+
+func defers() {
+	for j := 0; j < 3; j++ { // want `for-loop variable j used in defer or goroutine at line 28`
+		defer func() {
+			println(j)
+		}()
+	}
+}
+
+func runAsync(f func()) {
+	go f()
+}
+
+func closures(values []string) {
+	for _, v := range values { // want `range-loop variable v used in closure passed to (fixes.)?runAsync at line 41, which may run it asynchronously`
+		println(len(v))
+		runAsync(func() {
+			println(v)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package forloop
+
+import "sync"
+
+func main() {
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ { // want `for-loop variable i used in defer or goroutine at line 10`
+		wg.Add(1)
+		go func() {
+			println(i)
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+
+	for _, x := range []int{1, 2, 3} { // want `range-loop variable x used in defer or goroutine at line 20`
+		for i := 0; i < x; i++ {
+			wg.Add(1)
+			go func() {
+				println(x)
+				wg.Done()
+			}()
+		}
+	}
+	wg.Wait()
+
+	var j int
+	for j = 0; j < 5; j++ { // j is declared outside of the loop, so it's not tracked
+		defer func() {
+			println(j)
+		}()
+	}
+}
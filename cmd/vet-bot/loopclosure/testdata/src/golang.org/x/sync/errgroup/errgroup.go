@@ -0,0 +1,17 @@
+// Package errgroup is a stub of golang.org/x/sync/errgroup used for testing.
+package errgroup
+
+import "context"
+
+type Group struct{}
+
+func WithContext(ctx context.Context) (*Group, context.Context) { return &Group{}, ctx }
+
+func (g *Group) Go(f func() error) {}
+
+func (g *Group) Wait() error { return nil }
+
+// Runner is not an async sink, even though it has a Go method.
+type Runner struct{}
+
+func (r Runner) Go(f func() error) { f() }
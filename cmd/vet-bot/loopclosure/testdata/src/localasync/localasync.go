@@ -0,0 +1,59 @@
+package localasync
+
+func runAsync(f func()) {
+	go f()
+}
+
+func runLater(f func()) {
+	runAsync(f)
+}
+
+func runNow(f func()) {
+	f()
+}
+
+func main() {
+	for _, x := range []int{1, 2, 3} { // want `range-loop variable x used in closure passed to localasync.runLater at line 18, which may run it asynchronously`
+		runLater(func() {
+			println(x)
+		})
+	}
+
+	for i := 0; i < 3; i++ { // want `for-loop variable i used in closure passed to localasync.runAsync at line 24, which may run it asynchronously`
+		runAsync(func() {
+			println(i)
+		})
+	}
+
+	for _, x := range []int{1, 2, 3} {
+		runNow(func() {
+			println(x) // safe, since runNow never starts a goroutine
+		})
+	}
+}
+
+func runAndWait(f func(), done chan struct{}) {
+	go close(done)
+	f()
+}
+
+type task func()
+
+func runTask(t task) {
+	runAsync(t)
+}
+
+func more() {
+	done := make(chan struct{})
+	for _, x := range []int{1, 2, 3} {
+		runAndWait(func() {
+			println(x) // safe, since runAndWait only starts a goroutine which doesn't run f
+		}, done)
+	}
+
+	for _, x := range []int{1, 2, 3} { // want `range-loop variable x used in closure passed to localasync.runTask at line 56, which may run it asynchronously`
+		runTask(func() {
+			println(x)
+		})
+	}
+}
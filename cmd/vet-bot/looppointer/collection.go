@@ -0,0 +1,328 @@
+package looppointer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/github-vet/bots/cmd/vet-bot/fixes"
+	"golang.org/x/tools/go/analysis"
+)
+
+// CollectionKind describes the kind of collection ranged over by a range loop.
+type CollectionKind uint8
+
+const (
+	// CollectionUnknown indicates the kind of collection could not be determined.
+	CollectionUnknown CollectionKind = iota
+	// CollectionSlice indicates a range over a slice.
+	CollectionSlice
+	// CollectionArray indicates a range over an array, or a pointer to an array.
+	CollectionArray
+	// CollectionString indicates a range over a string.
+	CollectionString
+	// CollectionMap indicates a range over a map.
+	CollectionMap
+	// CollectionChannel indicates a range over a channel.
+	CollectionChannel
+)
+
+func (k CollectionKind) String() string {
+	switch k {
+	case CollectionSlice:
+		return "slice"
+	case CollectionArray:
+		return "array"
+	case CollectionString:
+		return "string"
+	case CollectionMap:
+		return "map"
+	case CollectionChannel:
+		return "channel"
+	default:
+		return "unknown"
+	}
+}
+
+// article returns the indefinite article used before the name of the kind.
+func (k CollectionKind) article() string {
+	if k == CollectionArray {
+		return "an"
+	}
+	return "a"
+}
+
+// classifyRange determines the kind of collection ranged over by the provided range statement. Syntactic hints are
+// used first; type information is only consulted if no hint is found and the package was type-checked.
+func classifyRange(info *types.Info, n *ast.RangeStmt) CollectionKind {
+	if kind := classifyExpr(n.X, make(map[*ast.Object]struct{})); kind != CollectionUnknown {
+		return kind
+	}
+	if info == nil {
+		return CollectionUnknown
+	}
+	if t := info.TypeOf(n.X); t != nil {
+		return classifyType(t)
+	}
+	return CollectionUnknown
+}
+
+// classifyExpr classifies the value of expr using only syntactic information. Identifiers are followed back to
+// their declaration in scope; seen guards against declarations which refer to one another.
+func classifyExpr(expr ast.Expr, seen map[*ast.Object]struct{}) CollectionKind {
+	switch typed := expr.(type) {
+	case *ast.ParenExpr:
+		return classifyExpr(typed.X, seen)
+	case *ast.BasicLit:
+		if typed.Kind == token.STRING {
+			return CollectionString
+		}
+	case *ast.CompositeLit:
+		return classifyTypeExpr(typed.Type, seen)
+	case *ast.UnaryExpr:
+		if kind := classifyExpr(typed.X, seen); kind == CollectionArray {
+			return CollectionArray // &arr
+		}
+	case *ast.SliceExpr:
+		switch classifyExpr(typed.X, seen) {
+		case CollectionString:
+			return CollectionString
+		case CollectionSlice, CollectionArray:
+			return CollectionSlice
+		}
+	case *ast.CallExpr:
+		if fun, ok := typed.Fun.(*ast.Ident); ok && fun.Obj == nil && len(typed.Args) > 0 {
+			switch fun.Name {
+			case "make", "new":
+				kind := classifyTypeExpr(typed.Args[0], seen)
+				if fun.Name == "new" && kind != CollectionArray {
+					return CollectionUnknown
+				}
+				return kind
+			case "append":
+				return CollectionSlice
+			}
+		}
+		return classifyTypeExpr(typed.Fun, seen) // conversions, e.g. []byte(s)
+	case *ast.Ident:
+		return classifyObject(typed.Obj, seen)
+	}
+	return CollectionUnknown
+}
+
+// classifyObject classifies the value of a variable from its declaration.
+func classifyObject(obj *ast.Object, seen map[*ast.Object]struct{}) CollectionKind {
+	if obj == nil || obj.Kind != ast.Var {
+		return CollectionUnknown
+	}
+	if _, ok := seen[obj]; ok {
+		return CollectionUnknown
+	}
+	seen[obj] = struct{}{}
+	switch decl := obj.Decl.(type) {
+	case *ast.Field: // parameters and results
+		return classifyTypeExpr(decl.Type, seen)
+	case *ast.ValueSpec:
+		if decl.Type != nil {
+			return classifyTypeExpr(decl.Type, seen)
+		}
+		if len(decl.Values) == len(decl.Names) {
+			for i, name := range decl.Names {
+				if name.Obj == obj {
+					return classifyExpr(decl.Values[i], seen)
+				}
+			}
+		}
+	case *ast.AssignStmt:
+		if len(decl.Lhs) == len(decl.Rhs) {
+			for i, lhs := range decl.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok && id.Obj == obj {
+					return classifyExpr(decl.Rhs[i], seen)
+				}
+			}
+		}
+	}
+	return CollectionUnknown
+}
+
+// classifyTypeExpr classifies values of the type described by expr. Named types are followed back to their
+// declaration in scope.
+func classifyTypeExpr(expr ast.Expr, seen map[*ast.Object]struct{}) CollectionKind {
+	switch typed := expr.(type) {
+	case *ast.ParenExpr:
+		return classifyTypeExpr(typed.X, seen)
+	case *ast.ArrayType:
+		if typed.Len == nil {
+			return CollectionSlice
+		}
+		return CollectionArray
+	case *ast.MapType:
+		return CollectionMap
+	case *ast.ChanType:
+		return CollectionChannel
+	case *ast.StarExpr:
+		if kind := classifyTypeExpr(typed.X, seen); kind == CollectionArray {
+			return CollectionArray
+		}
+	case *ast.Ident:
+		if typed.Obj == nil {
+			if typed.Name == "string" {
+				return CollectionString
+			}
+			return CollectionUnknown
+		}
+		if _, ok := seen[typed.Obj]; ok {
+			return CollectionUnknown
+		}
+		seen[typed.Obj] = struct{}{}
+		if spec, ok := typed.Obj.Decl.(*ast.TypeSpec); ok {
+			return classifyTypeExpr(spec.Type, seen)
+		}
+	}
+	return CollectionUnknown
+}
+
+// classifyType classifies values of the provided type.
+func classifyType(t types.Type) CollectionKind {
+	switch typed := t.Underlying().(type) {
+	case *types.Slice:
+		return CollectionSlice
+	case *types.Array:
+		return CollectionArray
+	case *types.Map:
+		return CollectionMap
+	case *types.Chan:
+		return CollectionChannel
+	case *types.Basic:
+		if typed.Info()&types.IsString != 0 {
+			return CollectionString
+		}
+	case *types.Pointer:
+		if _, ok := typed.Elem().Underlying().(*types.Array); ok {
+			return CollectionArray
+		}
+	}
+	return CollectionUnknown
+}
+
+// rewrite suggests a concrete rewrite which avoids taking the address of the provided range-loop variable.
+func rewrite(loop *ast.RangeStmt, kind CollectionKind, id *ast.Ident) string {
+	if index, ok := elementIndex(loop, kind, id); ok {
+		return fmt.Sprintf("refer to the element with &%s[%s] instead", types.ExprString(loop.X), index)
+	}
+	return fmt.Sprintf("copy it with %s := %s before taking its address", id.Name, id.Name)
+}
+
+// elementIndex returns the name of the index which can be used to refer to the element of the collection instead of
+// the provided range-loop variable, as in &xs[i]. ok is false if the element can't be referred to directly; either
+// because the variable is not the value of the loop, because the collection is not a slice or an array, because the
+// value is assigned to in the body of the loop, whose writes would otherwise be lost, or because the collection can't
+// safely be evaluated again inside the body of the loop.
+func elementIndex(loop *ast.RangeStmt, kind CollectionKind, id *ast.Ident) (index string, ok bool) {
+	if kind != CollectionSlice && kind != CollectionArray {
+		return "", false
+	}
+	value, isIdent := loop.Value.(*ast.Ident)
+	if !isIdent || value.Obj != id.Obj {
+		return "", false // the key is referenced; there is no element to refer to instead.
+	}
+	if fixes.AssignsDirectly(loop.Body, value.Obj) {
+		return "", false // the element would not reflect writes made to the value in the loop.
+	}
+	root := rootIdent(loop.X)
+	if root == nil || fixes.Declares(loop.Body, root.Name) {
+		return "", false
+	}
+	if key, isIdent := loop.Key.(*ast.Ident); isIdent && key.Name != "_" {
+		return key.Name, true
+	}
+	// the key is blank; choose a name for it which isn't used in the loop.
+	for _, name := range []string{"i", "idx", "index"} {
+		if name != root.Name && !fixes.Mentions(loop.Body, name) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// uses counts the identifiers within node which refer to obj.
+func uses(node ast.Node, obj *ast.Object) int {
+	count := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok && id.Obj == obj {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// rootIdent returns the identifier at the root of expr, provided expr is an identifier or a chain of selectors which
+// can be evaluated again without side-effects. It returns nil otherwise.
+func rootIdent(expr ast.Expr) *ast.Ident {
+	switch typed := expr.(type) {
+	case *ast.Ident:
+		return typed
+	case *ast.SelectorExpr:
+		return rootIdent(typed.X)
+	case *ast.ParenExpr:
+		return rootIdent(typed.X)
+	}
+	return nil
+}
+
+// suggestFix suggests a single fix which removes every reference to a range-loop variable found in the provided
+// loop. References to slice and array elements are rewritten to refer to the element directly; every other variable
+// is copied at the top of the body of the loop.
+func suggestFix(fset *token.FileSet, loop *ast.RangeStmt, kind CollectionKind, findings []Finding) []analysis.SuggestedFix {
+	var edits []analysis.TextEdit
+	var copies []string
+	copied := make(map[*ast.Object]bool)
+	renamedKey := false
+	rewritten := 0
+	for _, finding := range findings {
+		id := finding.ident
+		if id == nil {
+			continue
+		}
+		index, ok := elementIndex(loop, kind, id)
+		if !ok {
+			if !copied[id.Obj] {
+				copied[id.Obj] = true
+				copies = append(copies, id.Name)
+			}
+			continue
+		}
+		edits = append(edits, analysis.TextEdit{
+			Pos:     id.Pos(),
+			End:     id.End(),
+			NewText: []byte(fmt.Sprintf("%s[%s]", types.ExprString(loop.X), index)),
+		})
+		rewritten++
+		if key, isIdent := loop.Key.(*ast.Ident); isIdent && key.Name == "_" && !renamedKey {
+			renamedKey = true
+			edits = append(edits, analysis.TextEdit{Pos: key.Pos(), End: key.End(), NewText: []byte(index)})
+		}
+	}
+	if value, ok := loop.Value.(*ast.Ident); ok && rewritten > 0 && rewritten == uses(loop.Body, value.Obj) {
+		// every use of the value was rewritten; it must be removed since it would otherwise be unused.
+		edits = append(edits, analysis.TextEdit{Pos: loop.Key.End(), End: value.End()})
+	}
+	if pos := insertionPosition(loop.Body); len(copies) > 0 && pos.IsValid() {
+		edits = append(edits, fixes.CopyVars(fset, pos, copies))
+	}
+	if len(edits) == 0 {
+		return nil
+	}
+	return []analysis.SuggestedFix{{Message: "avoid referring to range-loop variables", TextEdits: edits}}
+}
+
+// describeCollection describes the collection ranged over by loop, and suggests a rewrite avoiding the reference to
+// the provided range-loop variable. The description is appended to the message for a finding.
+func describeCollection(loop *ast.RangeStmt, kind CollectionKind, id *ast.Ident) string {
+	if kind == CollectionUnknown {
+		return "; " + rewrite(loop, kind, id)
+	}
+	return fmt.Sprintf(" while ranging over %s %s; %s", kind.article(), kind, rewrite(loop, kind, id))
+}
@@ -9,10 +9,12 @@ import (
 
 	"github.com/github-vet/bots/cmd/vet-bot/acceptlist"
 	"github.com/github-vet/bots/cmd/vet-bot/callgraph"
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
 	"github.com/github-vet/bots/cmd/vet-bot/nogofunc"
 	"github.com/github-vet/bots/cmd/vet-bot/packid"
 	"github.com/github-vet/bots/cmd/vet-bot/pointerescapes"
 	"github.com/github-vet/bots/cmd/vet-bot/stats"
+	"github.com/github-vet/bots/cmd/vet-bot/summary"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
@@ -24,18 +26,21 @@ var Analyzer = &analysis.Analyzer{
 	Doc:              "checks for pointers to enclosing loop variables; modified for sweeping GitHub",
 	Run:              run,
 	RunDespiteErrors: true,
-	Requires:         []*analysis.Analyzer{inspect.Analyzer, packid.Analyzer, callgraph.Analyzer, nogofunc.Analyzer, pointerescapes.Analyzer},
+	Requires:         []*analysis.Analyzer{inspect.Analyzer, gomod.Analyzer, packid.Analyzer, callgraph.Analyzer, nogofunc.Analyzer, pointerescapes.Analyzer},
 }
 
 func run(pass *analysis.Pass) (interface{}, error) {
 	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
 
 	search := &Searcher{
-		Stats: make(map[token.Pos]*ast.RangeStmt),
+		Stats:       make(map[token.Pos]ast.Stmt),
+		Findings:    make(map[ast.Stmt][]Finding),
+		Collections: make(map[*ast.RangeStmt]CollectionKind),
 	}
 
 	nodeFilter := []ast.Node{
 		(*ast.RangeStmt)(nil),
+		(*ast.ForStmt)(nil),
 		(*ast.UnaryExpr)(nil),
 	}
 
@@ -45,9 +50,10 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		}
 		reason := search.check(n, stack, pass)
 		countReasonStats(reason)
-		return reason == ReasonNone // TODO: don't stop on first hit; this requires a way to aggregate multiple results into one issue description.
+		return true
 	})
 
+	search.report(pass)
 	return nil, nil
 }
 
@@ -67,13 +73,96 @@ func countReasonStats(reason Reason) {
 		stats.AddCount(stats.StatLooppointerReportsPointerReassigned, 1)
 	case ReasonPointerStoredInCompositeLit:
 		stats.AddCount(stats.StatLooppointerReportsCompositeLit, 1)
+	case ReasonCallSendsPtrOnChannel:
+		stats.AddCount(stats.StatLooppointerReportsChanSend, 1)
+	case ReasonCallAppendsPtr:
+		stats.AddCount(stats.StatLooppointerReportsAppend, 1)
+	case ReasonCallStoresPtrInIndex:
+		stats.AddCount(stats.StatLooppointerReportsIndexStore, 1)
+	case ReasonCallReturnsPtr:
+		stats.AddCount(stats.StatLooppointerReportsReturn, 1)
+	case ReasonCallStoresPtrInClosure:
+		stats.AddCount(stats.StatLooppointerReportsClosureVar, 1)
 	}
 }
 
-// Searcher stores the set of range loops found in the source code, keyed by its
-// position in the repository.
+// Searcher stores the loop variables found in the source code, keyed by their position in the repository, along
+// with the findings for each loop. Loops are either a *ast.RangeStmt or a three-clause *ast.ForStmt.
 type Searcher struct {
-	Stats map[token.Pos]*ast.RangeStmt
+	Stats    map[token.Pos]ast.Stmt
+	Findings map[ast.Stmt][]Finding
+	// Collections holds the kind of collection ranged over by each range loop.
+	Collections map[*ast.RangeStmt]CollectionKind
+	loops       []ast.Stmt // loops with findings, in the order they were first found
+}
+
+// Finding describes a single unsafe reference to a loop variable. Every Finding within the same loop is merged into
+// a single Diagnostic.
+type Finding struct {
+	Reason     Reason
+	Collection CollectionKind // kind of collection ranged over; CollectionUnknown for three-clause for loops
+	Pos        token.Pos      // position of the reference to the range-loop variable
+	End        token.Pos
+	Message    string
+	ExtraInfo  string
+	ident      *ast.Ident // the reference to the range-loop variable
+}
+
+func (s *Searcher) addFinding(rangeLoop ast.Stmt, id *ast.Ident, finding Finding) {
+	finding.ident = id
+	if _, ok := s.Findings[rangeLoop]; !ok {
+		s.loops = append(s.loops, rangeLoop)
+	}
+	if loop, ok := rangeLoop.(*ast.RangeStmt); ok {
+		finding.Collection = s.Collections[loop]
+	}
+	s.Findings[rangeLoop] = append(s.Findings[rangeLoop], finding)
+}
+
+// report reports a single Diagnostic for each loop with findings. The message of the first finding is used as
+// the message of the Diagnostic. Following the convention used by VetBot, Related[0] holds the name of the file and
+// Related[1] holds any extra information about the findings. Each finding is then listed in Related[2:].
+func (s *Searcher) report(pass *analysis.Pass) {
+	for _, rangeLoop := range s.loops {
+		findings := s.Findings[rangeLoop]
+		message := findings[0].Message
+		if len(findings) > 1 {
+			message = fmt.Sprintf("%s (and %d more)", message, len(findings)-1)
+		}
+		var extraInfo []string
+		for _, finding := range findings {
+			if finding.ExtraInfo == "" {
+				continue
+			}
+			if len(findings) > 1 {
+				extraInfo = append(extraInfo, finding.Message+":\n"+finding.ExtraInfo)
+			} else {
+				extraInfo = append(extraInfo, finding.ExtraInfo)
+			}
+		}
+		related := []analysis.RelatedInformation{
+			{Message: pass.Fset.File(rangeLoop.Pos()).Name()},
+			{Message: strings.Join(extraInfo, "\n")},
+		}
+		for _, finding := range findings {
+			related = append(related, analysis.RelatedInformation{
+				Pos:     finding.Pos,
+				End:     finding.End,
+				Message: finding.Message,
+			})
+		}
+		var suggested []analysis.SuggestedFix
+		if loop, ok := rangeLoop.(*ast.RangeStmt); ok {
+			suggested = suggestFix(pass.Fset, loop, s.Collections[loop], findings)
+		}
+		pass.Report(analysis.Diagnostic{
+			Pos:            rangeLoop.Pos(),
+			End:            rangeLoop.End(),
+			Message:        message,
+			Related:        related,
+			SuggestedFixes: suggested,
+		})
+	}
 }
 
 // Reason describes why an instance is being reported.
@@ -92,6 +181,19 @@ const (
 	ReasonCallPassesToThirdParty
 	// ReasonPointerStoredInCompositeLit indicates a pointer to a range-loop variable was used in a composite literal.
 	ReasonPointerStoredInCompositeLit
+	// ReasonCallSendsPtrOnChannel indicates some function call may send a reference to a range loop variable on a
+	// channel.
+	ReasonCallSendsPtrOnChannel
+	// ReasonCallAppendsPtr indicates some function call may append a reference to a range loop variable to a slice.
+	ReasonCallAppendsPtr
+	// ReasonCallStoresPtrInIndex indicates some function call may store a reference to a range loop variable in an
+	// element of a map or a slice.
+	ReasonCallStoresPtrInIndex
+	// ReasonCallReturnsPtr indicates some function call may return a reference to a range loop variable.
+	ReasonCallReturnsPtr
+	// ReasonCallStoresPtrInClosure indicates some function call may store a reference to a range loop variable in a
+	// variable captured by a closure.
+	ReasonCallStoresPtrInClosure
 )
 
 // Message returns a human-readable message, provided the name of the varaible and
@@ -108,34 +210,81 @@ func (r Reason) Message(name string, pos token.Position) string {
 		return fmt.Sprintf("function call at line %d passes reference to %s to third-party code", pos.Line, name)
 	case ReasonPointerStoredInCompositeLit:
 		return fmt.Sprintf("reference to %s was used in a composite literal at line %d", name, pos.Line)
+	case ReasonCallSendsPtrOnChannel:
+		return fmt.Sprintf("function call at line %d may send a reference to %s on a channel", pos.Line, name)
+	case ReasonCallAppendsPtr:
+		return fmt.Sprintf("function call at line %d may append a reference to %s to a slice", pos.Line, name)
+	case ReasonCallStoresPtrInIndex:
+		return fmt.Sprintf("function call at line %d may store a reference to %s in a map or slice element", pos.Line, name)
+	case ReasonCallReturnsPtr:
+		return fmt.Sprintf("function call at line %d may return a reference to %s", pos.Line, name)
+	case ReasonCallStoresPtrInClosure:
+		return fmt.Sprintf("function call at line %d may store a reference to %s in a variable captured by a closure", pos.Line, name)
 	default:
 		return ""
 	}
 }
 
+// ForLoopMessage returns a human-readable message for a variable declared in the init statement of a three-clause
+// for loop, provided the name of the variable and its position in the source code.
+func (r Reason) ForLoopMessage(name string, pos token.Position) string {
+	return r.Message("for-loop variable "+name, pos)
+}
+
+// message returns the message for a finding about the provided loop variable. Messages for range loops describe
+// the kind of collection ranged over and suggest a rewrite.
+func (s *Searcher) message(pass *analysis.Pass, loop ast.Stmt, reason Reason, id *ast.Ident) string {
+	pos := pass.Fset.Position(id.Pos())
+	switch typed := loop.(type) {
+	case *ast.ForStmt:
+		return reason.ForLoopMessage(id.Name, pos)
+	case *ast.RangeStmt:
+		return reason.Message(id.Name, pos) + describeCollection(typed, s.Collections[typed], id)
+	}
+	return reason.Message(id.Name, pos)
+}
+
 // TODO: passing the Reason back up is not very great.
 func (s *Searcher) check(n ast.Node, stack []ast.Node, pass *analysis.Pass) Reason {
 	switch typed := n.(type) {
 	case *ast.RangeStmt:
 		stats.AddCount(stats.StatRangeLoops, 1)
-		s.parseRangeStmt(typed)
+		s.parseRangeStmt(pass, typed)
+	case *ast.ForStmt:
+		stats.AddCount(stats.StatForLoops, 1)
+		s.parseForStmt(typed)
 	case *ast.UnaryExpr:
 		return s.checkUnaryExpr(typed, stack, pass)
 	}
 	return ReasonNone
 }
 
-func (s *Searcher) parseRangeStmt(n *ast.RangeStmt) {
+func (s *Searcher) parseRangeStmt(pass *analysis.Pass, n *ast.RangeStmt) {
+	s.Collections[n] = classifyRange(pass.TypesInfo, n)
 	s.addStat(n.Key, n)
 	s.addStat(n.Value, n)
 }
 
-func (s *Searcher) addStat(expr ast.Expr, n *ast.RangeStmt) {
+// parseForStmt records the variables declared in the init statement of a three-clause for loop, which are shared
+// between every iteration of the loop.
+func (s *Searcher) parseForStmt(n *ast.ForStmt) {
+	init, ok := n.Init.(*ast.AssignStmt)
+	if !ok || init.Tok != token.DEFINE {
+		return
+	}
+	for _, lhs := range init.Lhs {
+		s.addStat(lhs, n)
+	}
+}
+
+func (s *Searcher) addStat(expr ast.Expr, n ast.Stmt) {
 	if id, ok := expr.(*ast.Ident); ok {
 		s.Stats[id.Pos()] = n
 	}
 }
 
+// insertionPosition returns the position of the first statement in the provided block, or token.NoPos if the block
+// is empty.
 func insertionPosition(block *ast.BlockStmt) token.Pos {
 	if len(block.List) > 0 {
 		return block.List[0].Pos()
@@ -143,9 +292,12 @@ func insertionPosition(block *ast.BlockStmt) token.Pos {
 	return token.NoPos
 }
 
-func (s *Searcher) innermostLoop(stack []ast.Node) *ast.RangeStmt {
+func (s *Searcher) innermostLoop(stack []ast.Node) ast.Stmt {
 	for i := len(stack) - 1; i >= 0; i-- {
-		if typed, ok := stack[i].(*ast.RangeStmt); ok {
+		switch typed := stack[i].(type) {
+		case *ast.RangeStmt:
+			return typed
+		case *ast.ForStmt:
 			return typed
 		}
 	}
@@ -206,24 +358,38 @@ func (s *Searcher) checkUnaryExpr(unaryExpr *ast.UnaryExpr, stack []ast.Node, pa
 	}
 	rangeLoop := s.Stats[id.Obj.Pos()]
 
-	// we have found a referene to a range-loop variable; now vet it thoroughly.
-	if handleCompositeLit(pass, rangeLoop, stack, id) {
+	// since Go 1.22, each iteration of the loop has its own copy of the loop variables.
+	if pass.ResultOf[gomod.Analyzer].(*gomod.Result).ModuleFor(rangeLoop.Pos()).PerIterationLoopVars() {
+		stats.AddCount(stats.StatLooppointerSuppressedGo122, 1)
+		return ReasonNone
+	}
+
+	// we have found a referene to a loop variable; now vet it thoroughly.
+	reason := s.vetReference(pass, unaryExpr, innermostLoop, rangeLoop, stack, id)
+	if _, ok := rangeLoop.(*ast.ForStmt); ok && reason != ReasonNone {
+		stats.AddCount(stats.StatLooppointerForLoopHits, 1)
+	}
+	return reason
+}
+
+func (s *Searcher) vetReference(pass *analysis.Pass, unaryExpr *ast.UnaryExpr, innermostLoop ast.Stmt, rangeLoop ast.Stmt, stack []ast.Node, id *ast.Ident) Reason {
+	if s.handleCompositeLit(pass, rangeLoop, stack, id) {
 		return ReasonPointerStoredInCompositeLit
 	}
 
-	if reason, ok := handleAssignStmt(pass, unaryExpr, innermostLoop, rangeLoop, stack, id); ok {
+	if reason, ok := s.handleAssignStmt(pass, unaryExpr, innermostLoop, rangeLoop, stack, id); ok {
 		return reason
 	}
 
-	return handleCallExpr(pass, unaryExpr, rangeLoop, stack, id)
+	return s.handleCallExpr(pass, unaryExpr, rangeLoop, stack, id)
 }
 
 // handleCompositeLit handles the case where a reference to a range-loop variable is used inside a composite literal
 // within the body of the range loop. It returns true if an issue was reported.
-func handleCompositeLit(pass *analysis.Pass, rangeLoop *ast.RangeStmt, stack []ast.Node, id *ast.Ident) bool {
+func (s *Searcher) handleCompositeLit(pass *analysis.Pass, rangeLoop ast.Stmt, stack []ast.Node, id *ast.Ident) bool {
 	compositeLit := innermostCompositeLit(stack)
 	if compositeLit != nil {
-		reportBasic(pass, rangeLoop, ReasonPointerStoredInCompositeLit, id)
+		s.reportBasic(pass, rangeLoop, ReasonPointerStoredInCompositeLit, id)
 		return true
 	}
 	return false
@@ -232,7 +398,7 @@ func handleCompositeLit(pass *analysis.Pass, rangeLoop *ast.RangeStmt, stack []a
 // handleAssignStmt handles the case where a reference to a range-loop variable appears on the RHS of an assignment
 // within the body of the range-loop. It returns any reason the assignment may be dangerous; ok is true only if the
 // unaryExpr passed was found within an assignment statement.
-func handleAssignStmt(pass *analysis.Pass, unaryExpr *ast.UnaryExpr, innermostLoop *ast.RangeStmt, rangeLoop *ast.RangeStmt, stack []ast.Node, id *ast.Ident) (reason Reason, ok bool) {
+func (s *Searcher) handleAssignStmt(pass *analysis.Pass, unaryExpr *ast.UnaryExpr, innermostLoop ast.Stmt, rangeLoop ast.Stmt, stack []ast.Node, id *ast.Ident) (reason Reason, ok bool) {
 	assignStmt, child := innermostAssignStmt(stack)
 
 	reason, ok = ReasonNone, false
@@ -246,11 +412,20 @@ func handleAssignStmt(pass *analysis.Pass, unaryExpr *ast.UnaryExpr, innermostLo
 			return
 		}
 		for _, expr := range assignStmt.Rhs {
-			if expr.Pos() == child.Pos() && child.Pos() == unaryExpr.Pos() {
-				reportBasic(pass, rangeLoop, ReasonPointerReassigned, id)
+			if expr.Pos() != child.Pos() {
+				continue
+			}
+			if child.Pos() == unaryExpr.Pos() {
+				s.reportBasic(pass, rangeLoop, ReasonPointerReassigned, id)
 				reason = ReasonPointerReassigned
 				return
 			}
+			// the result of a function which returns the reference is assigned.
+			if call, isCall := child.(*ast.CallExpr); isCall && returnsArg(pass, call, unaryExpr) {
+				s.reportReturnedPtrSuspicion(pass, rangeLoop, call, id)
+				reason = ReasonCallReturnsPtr
+				return
+			}
 		}
 		return
 	}
@@ -258,7 +433,7 @@ func handleAssignStmt(pass *analysis.Pass, unaryExpr *ast.UnaryExpr, innermostLo
 }
 
 // handleCallExpr handles the case where a reference to a range-loop variable is used within a function call.
-func handleCallExpr(pass *analysis.Pass, unaryExpr *ast.UnaryExpr, rangeLoop *ast.RangeStmt, stack []ast.Node, id *ast.Ident) Reason {
+func (s *Searcher) handleCallExpr(pass *analysis.Pass, unaryExpr *ast.UnaryExpr, rangeLoop ast.Stmt, stack []ast.Node, id *ast.Ident) Reason {
 
 	callExpr := innermostCallExpr(stack)
 	if callExpr == nil {
@@ -270,7 +445,7 @@ func handleCallExpr(pass *analysis.Pass, unaryExpr *ast.UnaryExpr, rangeLoop *as
 	}
 
 	// check if CallExpr is acceptlisted, ignore it if so.
-	if acceptlist.GlobalAcceptList != nil {
+	if acceptlist.GlobalAcceptList() != nil {
 		packageResolver := pass.ResultOf[packid.Analyzer].(*packid.PackageResolver)
 		if acceptlist.IgnoreCall(packageResolver, callExpr, stack) {
 			return ReasonNone
@@ -281,10 +456,10 @@ func handleCallExpr(pass *analysis.Pass, unaryExpr *ast.UnaryExpr, rangeLoop *as
 	asyncFuncs := pass.ResultOf[nogofunc.Analyzer].(*nogofunc.Result).AsyncSignatures
 	safePtrs := pass.ResultOf[pointerescapes.Analyzer].(*pointerescapes.Result).SafePtrs
 
-	sig := callgraph.SignatureFromCallExpr(callExpr)
+	sig := pass.ResultOf[callgraph.Analyzer].(*callgraph.Result).CallSignature(callExpr)
 
 	if _, ok := asyncFuncs[sig]; ok {
-		reportAsyncSuspicion(pass, rangeLoop, callExpr, id)
+		s.reportAsyncSuspicion(pass, rangeLoop, callExpr, id)
 		return ReasonCallMaybeAsync
 	}
 	callIdx := -1
@@ -297,74 +472,157 @@ func handleCallExpr(pass *analysis.Pass, unaryExpr *ast.UnaryExpr, rangeLoop *as
 	if callIdx == -1 {
 		return ReasonNone // no matching argument was found; unary Expr was nested more deeply.
 	}
+	packageResolver := pass.ResultOf[packid.Analyzer].(*packid.PackageResolver)
+	if acceptlist.SafeArg(packageResolver, callExpr, stack, callIdx) {
+		return ReasonNone // the accept list says this argument of the third-party function is safe.
+	}
+	if summary.SafeArg(packageResolver, callExpr, stack, callIdx) {
+		return ReasonNone // the summary of this third-party function proves the argument is safe.
+	}
+	if acceptlist.StartsGoroutine(packageResolver, callExpr, stack) {
+		s.reportBasic(pass, rangeLoop, ReasonCallMaybeAsync, id)
+		return ReasonCallMaybeAsync
+	}
 	for _, safeIdx := range safePtrs[sig] {
 		if callIdx == safeIdx {
 			return ReasonNone // we know passing a pointer in this position is safe.
 		}
 	}
-	return reportEscapedPtrSuspicion(pass, rangeLoop, callExpr, id)
+	return s.reportEscapedPtrSuspicion(pass, rangeLoop, callExpr, id)
 }
 
 // reportEscapedPtrSuspicion validates the suspicion and also reports when a function may allow its pointer argument
 // to escape in some manner.
-func reportEscapedPtrSuspicion(pass *analysis.Pass, rangeLoop *ast.RangeStmt, call *ast.CallExpr, id *ast.Ident) Reason {
+func (s *Searcher) reportEscapedPtrSuspicion(pass *analysis.Pass, rangeLoop ast.Stmt, call *ast.CallExpr, id *ast.Ident) Reason {
 	dangerGraph := &pass.ResultOf[pointerescapes.Analyzer].(*pointerescapes.Result).DangerGraph
 	writesPtr := pass.ResultOf[pointerescapes.Analyzer].(*pointerescapes.Result).WritesPtr
+	escapes := pass.ResultOf[pointerescapes.Analyzer].(*pointerescapes.Result).Escapes
 	thirdPartyPtrPassed := pass.ResultOf[pointerescapes.Analyzer].(*pointerescapes.Result).ThirdPartyPtrPassed
+	returnedPtrs := pass.ResultOf[pointerescapes.Analyzer].(*pointerescapes.Result).ReturnedPtrs
 
-	sig := callgraph.SignatureFromCallExpr(call)
-	ptrWriteGraph := make(map[string]map[string]struct{})
-	thirdPartyGraph := make(map[string]map[string]struct{})
+	sig := pass.ResultOf[callgraph.Analyzer].(*callgraph.Result).CallSignature(call)
+	ptrWriteGraph := make(callgraph.PathGraph)
+	thirdPartyGraph := make(callgraph.PathGraph)
 
 	var reason Reason
 	err := dangerGraph.BFSWithStack(sig, func(sig callgraph.Signature, stack []callgraph.Signature) {
 		if _, ok := writesPtr[sig]; ok {
-			reason = ReasonCallMayWritePtr
-			addPathToGraph(ptrWriteGraph, stack)
+			reason = reasonForEscapes(escapes[sig])
+			ptrWriteGraph.AddPath(stack)
+		}
+		if len(returnedPtrs[sig]) > 0 && anyWritesPtr(writesPtr, stack[:len(stack)-1]) {
+			// a caller on the path stores the pointer returned by this function.
+			if reason == ReasonNone {
+				reason = ReasonCallReturnsPtr
+			}
+			ptrWriteGraph.AddPath(stack)
 		}
 		if _, ok := thirdPartyPtrPassed[sig]; ok {
 			reason = ReasonCallPassesToThirdParty
-			addPathToGraph(thirdPartyGraph, stack)
+			thirdPartyGraph.AddPath(stack)
 		}
 	})
 
 	var thirdPartyReport string
 	if err == callgraph.ErrSignatureMissing {
+		reason = ReasonCallPassesToThirdParty
 		thirdPartyReport = fmt.Sprintf("root signature %v was not found in the callgraph; reference was passed directly to third-party code", sig)
 	}
 
 	report := strings.Join([]string{
-		reportPathGraph(ptrWriteGraph, "function which writes a pointer argument"),
-		reportPathGraph(thirdPartyGraph, "function which passes a pointer to third-party code"),
+		ptrWriteGraph.Report("function which writes a pointer argument"),
+		thirdPartyGraph.Report("function which passes a pointer to third-party code"),
 		thirdPartyReport,
 	}, "\n")
 
-	pass.Report(analysis.Diagnostic{
-		Pos:     rangeLoop.Pos(),
-		End:     rangeLoop.End(),
-		Message: reason.Message(id.Name, pass.Fset.Position(id.Pos())),
-
-		Related: []analysis.RelatedInformation{
-			{Message: pass.Fset.File(call.Pos()).Name()},
-			{Message: report},
-		},
+	s.addFinding(rangeLoop, id, Finding{
+		Reason:    reason,
+		Pos:       id.Pos(),
+		End:       id.End(),
+		Message:   s.message(pass, rangeLoop, reason, id),
+		ExtraInfo: report,
 	})
 	return reason
 }
 
+// anyWritesPtr returns true if any of the provided signatures was found to write a pointer.
+func anyWritesPtr(writesPtr map[callgraph.Signature]struct{}, sigs []callgraph.Signature) bool {
+	for _, sig := range sigs {
+		if _, ok := writesPtr[sig]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// reportReturnedPtrSuspicion reports when the result of a function which may return a reference to a range-loop
+// variable is stored.
+func (s *Searcher) reportReturnedPtrSuspicion(pass *analysis.Pass, rangeLoop ast.Stmt, call *ast.CallExpr, id *ast.Ident) {
+	dangerGraph := &pass.ResultOf[pointerescapes.Analyzer].(*pointerescapes.Result).DangerGraph
+	returnedPtrs := pass.ResultOf[pointerescapes.Analyzer].(*pointerescapes.Result).ReturnedPtrs
+
+	sig := pass.ResultOf[callgraph.Analyzer].(*callgraph.Result).CallSignature(call)
+	returnGraph := make(callgraph.PathGraph)
+	dangerGraph.BFSWithStack(sig, func(sig callgraph.Signature, stack []callgraph.Signature) {
+		if len(returnedPtrs[sig]) > 0 {
+			returnGraph.AddPath(stack)
+		}
+	})
+
+	s.addFinding(rangeLoop, id, Finding{
+		Reason:    ReasonCallReturnsPtr,
+		Pos:       id.Pos(),
+		End:       id.End(),
+		Message:   s.message(pass, rangeLoop, ReasonCallReturnsPtr, id),
+		ExtraInfo: returnGraph.Report("function which returns a pointer argument"),
+	})
+}
+
+// returnsArg returns true if the provided call may return the argument found at the position of arg.
+func returnsArg(pass *analysis.Pass, call *ast.CallExpr, arg ast.Expr) bool {
+	graph := pass.ResultOf[callgraph.Analyzer].(*callgraph.Result)
+	returnedPtrs := pass.ResultOf[pointerescapes.Analyzer].(*pointerescapes.Result).ReturnedPtrs
+	for _, idx := range returnedPtrs[graph.CallSignature(call)] {
+		if idx < len(call.Args) && call.Args[idx].Pos() == arg.Pos() {
+			return true
+		}
+	}
+	return false
+}
+
+// reasonForEscapes describes the most specific way in which a function writes its pointer arguments.
+func reasonForEscapes(kinds map[pointerescapes.EscapeKind]struct{}) Reason {
+	for _, candidate := range []struct {
+		kind   pointerescapes.EscapeKind
+		reason Reason
+	}{
+		{pointerescapes.EscapeChanSend, ReasonCallSendsPtrOnChannel},
+		{pointerescapes.EscapeAppend, ReasonCallAppendsPtr},
+		{pointerescapes.EscapeIndex, ReasonCallStoresPtrInIndex},
+		{pointerescapes.EscapeClosureVar, ReasonCallStoresPtrInClosure},
+		{pointerescapes.EscapeAssign, ReasonCallMayWritePtr},
+		{pointerescapes.EscapeCompositeLit, ReasonCallMayWritePtr},
+	} {
+		if _, ok := kinds[candidate.kind]; ok {
+			return candidate.reason
+		}
+	}
+	return ReasonCallMayWritePtr
+}
+
 // reportAsyncSuspicion validates the suspicion and also reports the finding that a function may lead to starting
 // a goroutine.
-func reportAsyncSuspicion(pass *analysis.Pass, rangeLoop *ast.RangeStmt, call *ast.CallExpr, id *ast.Ident) {
+func (s *Searcher) reportAsyncSuspicion(pass *analysis.Pass, rangeLoop ast.Stmt, call *ast.CallExpr, id *ast.Ident) {
 	// TODO: this also must report whenever a noted "third-party" signature is reached in the callgraph.
 	startsGoroutine := pass.ResultOf[nogofunc.Analyzer].(*nogofunc.Result).ContainsGoStmt
 	cg := pass.ResultOf[callgraph.Analyzer].(*callgraph.Result).ApproxCallGraph
 
-	sig := callgraph.SignatureFromCallExpr(call)
-	sigGraph := make(map[string]map[string]struct{})
+	sig := pass.ResultOf[callgraph.Analyzer].(*callgraph.Result).CallSignature(call)
+	sigGraph := make(callgraph.PathGraph)
 
 	err := cg.BFSWithStack(sig, func(sig callgraph.Signature, stack []callgraph.Signature) {
 		if _, ok := startsGoroutine[sig]; ok {
-			addPathToGraph(sigGraph, stack)
+			sigGraph.AddPath(stack)
 		}
 	})
 
@@ -373,67 +631,22 @@ func reportAsyncSuspicion(pass *analysis.Pass, rangeLoop *ast.RangeStmt, call *a
 		// TODO?: report possible third-party code?
 	}
 
-	pass.Report(analysis.Diagnostic{
-		Pos:     rangeLoop.Pos(),
-		End:     rangeLoop.End(),
-		Message: ReasonCallMaybeAsync.Message(id.Name, pass.Fset.Position(id.Pos())),
-
-		Related: []analysis.RelatedInformation{
-			{Message: pass.Fset.File(call.Pos()).Name()},
-			{Message: reportPathGraph(sigGraph, "function calling a goroutine")},
-		},
+	s.addFinding(rangeLoop, id, Finding{
+		Reason:    ReasonCallMaybeAsync,
+		Pos:       id.Pos(),
+		End:       id.End(),
+		Message:   s.message(pass, rangeLoop, ReasonCallMaybeAsync, id),
+		ExtraInfo: sigGraph.Report("function calling a goroutine"),
 	})
 }
 
-func addPathToGraph(graph map[string]map[string]struct{}, path []callgraph.Signature) {
-	for i := 0; i < len(path); i++ {
-		from := fmt.Sprintf("(%s, %d)", path[i].Name, path[i].Arity)
-		// put edge from -> to into graph
-		fromNeighbors, ok := graph[from]
-		if !ok {
-			graph[from] = make(map[string]struct{})
-			fromNeighbors = graph[from]
-		}
-		if i != len(path)-1 {
-			to := fmt.Sprintf("(%s, %d)", path[i+1].Name, path[i+1].Arity)
-			fromNeighbors[to] = struct{}{}
-		}
-	}
-}
-
-func reportPathGraph(pathGraph map[string]map[string]struct{}, badFuncPhrase string) string {
-	var sb strings.Builder
-	if len(pathGraph) == 0 {
-		sb.WriteString("No path was found through the callgraph that could lead to a ")
-		sb.WriteString(badFuncPhrase)
-		sb.WriteString(".\n")
-		return sb.String()
-	}
-	sb.WriteString("The following graphviz dot graph describes paths through the callgraph that could lead to a ")
-	sb.WriteString(badFuncPhrase)
-	sb.WriteString(":\n")
-	sb.WriteString("digraph G {\n")
-	for from, neighbors := range pathGraph {
-		fmt.Fprintf(&sb, `  "%s" -> {`, from)
-		for to := range neighbors {
-			fmt.Fprintf(&sb, `"%s";`, to)
-		}
-		sb.WriteString("}\n")
-	}
-	sb.WriteString("}\n")
-	return sb.String()
-}
-
 // TODO: remove this function and make it more specific....
-func reportBasic(pass *analysis.Pass, rangeLoop *ast.RangeStmt, reason Reason, id *ast.Ident) {
-	pass.Report(analysis.Diagnostic{
-		Pos:     rangeLoop.Pos(),
-		End:     rangeLoop.End(),
-		Message: reason.Message(id.Name, pass.Fset.Position(id.Pos())),
-
-		Related: []analysis.RelatedInformation{
-			{Message: pass.Fset.File(id.Pos()).Name()},
-		},
+func (s *Searcher) reportBasic(pass *analysis.Pass, rangeLoop ast.Stmt, reason Reason, id *ast.Ident) {
+	s.addFinding(rangeLoop, id, Finding{
+		Reason:  reason,
+		Pos:     id.Pos(),
+		End:     id.End(),
+		Message: s.message(pass, rangeLoop, reason, id),
 	})
 }
 
@@ -1,37 +1,46 @@
 package looppointer_test
 
 import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/github-vet/bots/cmd/vet-bot/acceptlist"
+	"github.com/github-vet/bots/cmd/vet-bot/driver"
+	"github.com/github-vet/bots/cmd/vet-bot/driver/drivertest"
 	"github.com/github-vet/bots/cmd/vet-bot/looppointer"
 	"github.com/github-vet/bots/cmd/vet-bot/stats"
+	"github.com/github-vet/bots/cmd/vet-bot/summary"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/analysistest"
 )
 
 func Test(t *testing.T) {
 	testdata := analysistest.TestData()
-	acceptlist.GlobalAcceptList = &acceptlist.AcceptList{
+	acceptlist.SetGlobalAcceptList(&acceptlist.AcceptList{
 		Accept: map[string]map[string]struct{}{
 			"fmt": {
 				"Printf": {},
 			},
 		},
-	}
+	})
 	analysistest.Run(t, testdata, looppointer.Analyzer, "devtest")
 }
 
 func TestStats(t *testing.T) {
 	stats.Clear()
 	testdata := analysistest.TestData()
-	acceptlist.GlobalAcceptList = &acceptlist.AcceptList{
+	acceptlist.SetGlobalAcceptList(&acceptlist.AcceptList{
 		Accept: map[string]map[string]struct{}{
 			"fmt": {
 				"Printf": {},
 			},
 		},
-	}
+	})
 	analysistest.Run(t, testdata, looppointer.Analyzer, "stattest")
 	// validate only the stats looppointer is responsible for counting
 	assert.EqualValues(t, stats.GetCount(stats.StatFuncDecl), 27)
@@ -49,3 +58,127 @@ func TestStats(t *testing.T) {
 	assert.EqualValues(t, stats.GetCount(stats.StatLooppointerReportsPointerReassigned), 1)
 	assert.EqualValues(t, stats.GetCount(stats.StatLooppointerReportsCompositeLit), 2)
 }
+
+func TestMultipleFindings(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, looppointer.Analyzer, "devtest")
+	var related []string
+	for _, result := range results {
+		for _, d := range result.Diagnostics {
+			if strings.HasSuffix(d.Message, "(and 2 more)") {
+				for _, r := range d.Related[2:] {
+					related = append(related, r.Message)
+				}
+			}
+		}
+	}
+	assert.Equal(t, []string{
+		"reference to z was used in a composite literal at line 175 while ranging over a slice; copy it with z := z before taking its address",
+		"reference to z is reassigned at line 176 while ranging over a slice; copy it with z := z before taking its address",
+		"function call which takes a reference to z at line 177 may start a goroutine while ranging over a slice; copy it with z := z before taking its address",
+	}, related)
+}
+
+func TestTypeChecked(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, looppointer.Analyzer, "typed")
+}
+
+func TestForLoop(t *testing.T) {
+	stats.Clear()
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, looppointer.Analyzer, "forloop")
+	assert.EqualValues(t, 5, stats.GetCount(stats.StatForLoops))
+	assert.EqualValues(t, 3, stats.GetCount(stats.StatLooppointerHits))
+	assert.EqualValues(t, 2, stats.GetCount(stats.StatLooppointerForLoopHits))
+}
+
+func TestGo122(t *testing.T) {
+	stats.Clear()
+	reported := drivertest.Run(t, filepath.Join(analysistest.TestData(), "src", "go122"), looppointer.Analyzer)
+	assert.Empty(t, reported)
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatLooppointerSuppressedGo122))
+	assert.EqualValues(t, 0, stats.GetCount(stats.StatLooppointerHits))
+}
+
+func TestEscapes(t *testing.T) {
+	stats.Clear()
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, looppointer.Analyzer, "escapes")
+	assert.EqualValues(t, 2, stats.GetCount(stats.StatLooppointerReportsChanSend))
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatLooppointerReportsAppend))
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatLooppointerReportsIndexStore))
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatLooppointerReportsWritePtr))
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatLooppointerReportsReturn))
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatLooppointerReportsClosureVar))
+}
+
+func TestReturnFlow(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, looppointer.Analyzer, "escapes")
+	var extraInfo string
+	for _, result := range results {
+		for _, d := range result.Diagnostics {
+			if strings.Contains(d.Message, "line 76") {
+				extraInfo = d.Related[1].Message
+			}
+		}
+	}
+	assert.Contains(t, extraInfo, `"(escapes.storesReturned, 1)" -> {"(escapes.returnsReturned, 1)";}`)
+	assert.Contains(t, extraInfo, `"(escapes.returnsReturned, 1)" -> {"(escapes.returns, 1)";}`)
+}
+
+func TestSummary(t *testing.T) {
+	summary.GlobalSummary = summary.New()
+	summary.GlobalSummary.Add("encoding/json", "Unmarshal", summary.Func{Arity: 2, SafePtrs: []int{1}, NoGoroutines: true})
+	summary.GlobalSummary.Add("fmt", "Sscan", summary.Func{Arity: 2, SafePtrs: []int{1}})
+	t.Cleanup(func() { summary.GlobalSummary = nil })
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, looppointer.Analyzer, "summarized")
+}
+
+func TestAcceptListArgs(t *testing.T) {
+	acceptlist.SetGlobalAcceptList(&acceptlist.AcceptList{
+		Accept: map[string]map[string]struct{}{
+			"encoding/json": {
+				"Decoder.Decode": {},
+			},
+		},
+		Entries: map[string]map[string]acceptlist.Entry{
+			"encoding/json": {
+				"Unmarshal": {Name: "Unmarshal", SafeArgs: []int{1}},
+			},
+			"fmt": {
+				"Sscan":  {Name: "Sscan", SafeArgs: []int{1}, Goroutines: true},
+				"Sscanf": {Name: "Sscanf", SafeArgs: []int{1}},
+			},
+		},
+	})
+	t.Cleanup(func() { acceptlist.SetGlobalAcceptList(nil) })
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, looppointer.Analyzer, "acceptargs")
+}
+
+func TestCollections(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, looppointer.Analyzer, "collections")
+}
+
+func TestCollectionsUntyped(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filepath.Join(analysistest.TestData(), "src", "collections", "collections.go"), nil, 0)
+	assert.NoError(t, err)
+	messages := make(map[int]string)
+	_, err = driver.Run(fset, []*ast.File{file}, nil, []*analysis.Analyzer{looppointer.Analyzer}, func(d analysis.Diagnostic) {
+		messages[fset.Position(d.Pos).Line] = d.Message
+	})
+	assert.NoError(t, err)
+	assert.Len(t, messages, 13)
+	// without type information, only syntactic hints are available.
+	assert.Equal(t, "reference to v is reassigned at line 18 while ranging over a slice; refer to the element with &xs[i] instead", messages[17])
+	assert.Equal(t, "reference to v is reassigned at line 32 while ranging over an array; refer to the element with &g[i] instead", messages[31])
+	assert.Equal(t, "reference to v is reassigned at line 69; copy it with v := v before taking its address", messages[68])
+	assert.Equal(t, "reference to v is reassigned at line 73; copy it with v := v before taking its address", messages[71])
+	assert.Equal(t, "reference to i is reassigned at line 79; copy it with i := i before taking its address", messages[78])
+	assert.Equal(t, "reference to v is reassigned at line 86 while ranging over a slice; copy it with v := v before taking its address", messages[84])
+}
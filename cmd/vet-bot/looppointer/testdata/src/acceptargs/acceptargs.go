@@ -0,0 +1,28 @@
+package acceptargs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func main() {
+	data := []byte("1")
+	for _, x := range []int{1, 2, 3} {
+		json.Unmarshal(data, &x) // safe, since the accept list says Unmarshal does not store its second argument
+	}
+	for _, x := range []int{1, 2, 3} { // want `function call at line 15 passes reference to x to third-party code`
+		fmt.Sscanf("1", "%d", &x) // only the format is listed as safe
+	}
+	for _, x := range []int{1, 2, 3} { // want `function call which takes a reference to x at line 18 may start a goroutine`
+		fmt.Sscan("1", &x)
+	}
+	dec := json.NewDecoder(os.Stdin)
+	for _, x := range []int{1, 2, 3} {
+		dec.Decode(&x) // safe, since the accept list names Decoder.Decode
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, x := range []int{1, 2, 3} { // want `function call at line 26 passes reference to x to third-party code`
+		enc.Encode(&x)
+	}
+}
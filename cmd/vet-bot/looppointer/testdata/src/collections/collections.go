@@ -0,0 +1,89 @@
+package collections
+
+type Grid [3]int
+
+type Bag struct {
+	items []int
+}
+
+var (
+	ptr   *int
+	sptr  *string
+	rptr  *rune
+	items []int
+)
+
+func overSlice(xs []int) {
+	for i, v := range xs { // want `reference to v is reassigned at line 18 while ranging over a slice; refer to the element with &xs\[i\] instead`
+		ptr = &v
+		_ = i
+	}
+	for _, v := range xs { // want `reference to v is reassigned at line 22 while ranging over a slice; refer to the element with &xs\[i\] instead`
+		ptr = &v
+	}
+	for i := range items { // want `reference to i is reassigned at line 25 while ranging over a slice; copy it with i := i before taking its address`
+		ptr = &i
+	}
+}
+
+func overArray() {
+	var g Grid
+	for _, v := range g { // want `reference to v is reassigned at line 32 while ranging over an array; refer to the element with &g\[i\] instead`
+		ptr = &v
+	}
+	for _, v := range &g { // want `reference to v is reassigned at line 35 while ranging over an array; copy it with v := v before taking its address`
+		ptr = &v
+	}
+}
+
+func overMap() {
+	m := map[string]int{}
+	for k, v := range m { // want `reference to v is reassigned at line 42 while ranging over a map; copy it with v := v before taking its address`
+		ptr = &v
+		_ = k
+	}
+	for k := range make(map[string]bool) { // want `reference to k is reassigned at line 46 while ranging over a map; copy it with k := k before taking its address`
+		sptr = &k
+	}
+}
+
+func overChannel() {
+	ch := make(chan int)
+	for v := range ch { // want `reference to v is reassigned at line 53 while ranging over a channel; copy it with v := v before taking its address`
+		ptr = &v
+	}
+}
+
+func overString(s string) {
+	for _, r := range s { // want `reference to r is reassigned at line 59 while ranging over a string; copy it with r := r before taking its address`
+		rptr = &r
+	}
+}
+
+func values() []int {
+	return nil
+}
+
+func typesOnly(b *Bag) {
+	for _, v := range values() { // want `reference to v is reassigned at line 69 while ranging over a slice; copy it with v := v before taking its address`
+		ptr = &v
+	}
+	for i, v := range b.items { // want `reference to v is reassigned at line 73 while ranging over a slice; refer to the element with &b.items\[i\] instead`
+		_ = i
+		ptr = &v
+	}
+}
+
+func overInt() {
+	for i := range 10 { // want `reference to i is reassigned at line 79; copy it with i := i before taking its address`
+		ptr = &i
+	}
+}
+
+func reassigned(xs []int) {
+	for i, v := range xs { // want `reference to v is reassigned at line 86 while ranging over a slice; copy it with v := v before taking its address`
+		v *= 2
+		ptr = &v
+		_ = i
+	}
+}
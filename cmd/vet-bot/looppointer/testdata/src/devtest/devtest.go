@@ -168,3 +168,13 @@ func callThirdPartyAcceptListed1(x *int) {
 func callThirdPartyAcceptListed2(x *int) {
 	fmt.Printf("%v", x) // fmt.Printf *is* accept-listed;
 }
+
+func multipleFindings() {
+	var x *int
+	for _, z := range []int{1} { // want `reference to z was used in a composite literal at line 175 while ranging over a slice; copy it with z := z before taking its address \(and 2 more\)`
+		useUnsafeStruct(UnsafeStruct{&z})
+		x = &z
+		unsafeAsync(&z)
+	}
+	fmt.Println(x)
+}
@@ -0,0 +1,78 @@
+package escapes
+
+var (
+	ptr   *int
+	ptrs  []*int
+	byKey map[string]*int
+	ch    chan *int
+)
+
+func sends(x *int) {
+	ch <- x
+}
+
+func appends(x *int) {
+	ptrs = append(ptrs, x)
+}
+
+func indexes(x *int) {
+	byKey["key"] = x
+}
+
+func returns(x *int) *int {
+	return x
+}
+
+func captures(x *int) func() *int {
+	var y *int
+	return func() *int {
+		y = x
+		return y
+	}
+}
+
+func passes(x *int) {
+	sends(x)
+}
+
+func reads(x *int) int {
+	return *x
+}
+
+func returnsReturned(x *int) *int {
+	return returns(x)
+}
+
+func storesReturned(x *int) {
+	ptr = returnsReturned(x)
+}
+
+func main() {
+	for _, x := range []int{1, 2, 3} { // want `function call at line 52 may send a reference to x on a channel`
+		sends(&x)
+	}
+	for _, x := range []int{1, 2, 3} { // want `function call at line 55 may append a reference to x to a slice`
+		appends(&x)
+	}
+	for _, x := range []int{1, 2, 3} { // want `function call at line 58 may store a reference to x in a map or slice element`
+		indexes(&x)
+	}
+	for _, x := range []int{1, 2, 3} { // want `function call at line 61 may return a reference to x`
+		ptr = returns(&x)
+	}
+	for _, x := range []int{1, 2, 3} { // want `function call at line 64 may store a reference to x in a variable captured by a closure`
+		captures(&x)
+	}
+	for _, x := range []int{1, 2, 3} { // want `function call at line 67 may send a reference to x on a channel`
+		passes(&x)
+	}
+	for _, x := range []int{1, 2, 3} {
+		reads(&x)
+	}
+	for _, x := range []int{1, 2, 3} {
+		println(returns(&x)) // safe, since the result is never stored
+	}
+	for _, x := range []int{1, 2, 3} { // want `function call at line 76 may store a reference to x`
+		storesReturned(&x)
+	}
+}
@@ -0,0 +1,40 @@
+package forloop
+
+type holder struct {
+	ptr *int
+}
+
+func main() {
+	var y *int
+	for i := 0; i < 10; i++ { // want `reference to for-loop variable i is reassigned at line 10`
+		y = &i
+	}
+	println(y)
+
+	var holders []holder
+	for i, j := 0, 10; i < j; i, j = i+1, j-1 { // want `reference to for-loop variable j was used in a composite literal at line 16`
+		holders = append(holders, holder{&j})
+	}
+	println(holders)
+
+	for i := 0; i < 10; i++ {
+		if i == 5 {
+			y = &i // safe because the loop is left immediately
+			break
+		}
+	}
+
+	for _, x := range []int{1, 2, 3} { // want `reference to x is reassigned at line 30`
+		for i := 0; i < x; i++ {
+			if i == 1 {
+				y = &x // unsafe, since break only exits the inner loop
+				break
+			}
+		}
+	}
+
+	var k int
+	for k = 0; k < 10; k++ { // k is declared outside of the loop, so it's not tracked
+		y = &k
+	}
+}
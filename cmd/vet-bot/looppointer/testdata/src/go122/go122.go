@@ -0,0 +1,10 @@
+package go122
+
+// This package is treated as part of a module which declares Go 1.22, so no findings are reported.
+func main() {
+	var y *int
+	for _, x := range []int{1, 2, 3} {
+		y = &x
+	}
+	println(y)
+}
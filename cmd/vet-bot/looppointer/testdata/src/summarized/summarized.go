@@ -0,0 +1,19 @@
+package summarized
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func main() {
+	data := []byte("1")
+	for _, x := range []int{1, 2, 3} {
+		json.Unmarshal(data, &x) // safe, since the summary proves Unmarshal does not store its second argument
+	}
+	for _, x := range []int{1, 2, 3} { // want `function call at line 14 passes reference to x to third-party code`
+		json.NewEncoder(nil).Encode(&x)
+	}
+	for _, x := range []int{1, 2, 3} { // want `function call at line 17 passes reference to x to third-party code`
+		fmt.Sscan("1", &x)
+	}
+}
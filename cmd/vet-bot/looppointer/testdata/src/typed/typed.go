@@ -0,0 +1,36 @@
+package typed
+
+type Writer struct {
+	p *int
+}
+
+func (w *Writer) store(p *int) {
+	w.p = p
+}
+
+type Reader struct {
+	x int
+}
+
+func (r *Reader) store(p *int) {
+	r.x = *p
+}
+
+type storer interface {
+	store(p *int)
+}
+
+func main() {
+	var r Reader
+	for _, x := range []int{1} { // the type-checker resolves this call to Reader.store, which is safe.
+		r.store(&x)
+	}
+	var w Writer
+	for _, x := range []int{1} { // want `function call at line 30 may store a reference to x`
+		w.store(&x)
+	}
+	var s storer = &r
+	for _, x := range []int{1} { // want `function call at line 34 may store a reference to x`
+		s.store(&x)
+	}
+}
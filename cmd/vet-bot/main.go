@@ -8,10 +8,14 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime/debug"
 	"sync"
+	"syscall"
 
 	"github.com/github-vet/bots/cmd/vet-bot/acceptlist"
+	"github.com/github-vet/bots/cmd/vet-bot/classify"
+	"github.com/github-vet/bots/cmd/vet-bot/summary"
 	"github.com/github-vet/bots/internal/db"
 	"github.com/github-vet/bots/internal/ratelimit"
 	"github.com/google/go-github/v32/github"
@@ -24,7 +28,8 @@ import (
 //
 // vetbot runs continuously, sampling from a list of GitHub repositories, downloading their contents, running
 // static analysis on every .go file they contain, and reporting any findings to the issue tracker of a hardcoded
-// GitHub repository.
+// GitHub repository. Findings can instead be recorded only in the database, or written to stdout as JSON lines or to
+// a SARIF file, by selecting a different sink via the -sink option.
 //
 // vetbot expects an environment variable named GITHUB_TOKEN which contains a valid personal access token used
 // to authenticate with the GitHub API.
@@ -37,6 +42,10 @@ import (
 // have been visted.
 //
 // vetbot also creates a log file named 'MM-DD-YYYY.log', using the system date.
+//
+// While sampling repositories, vetbot reloads the accept list between repositories whenever its file is modified or
+// vetbot receives SIGHUP. When vetting a single repository or a local path, the accept list is loaded once, and SIGHUP
+// is not handled, since there is no later repository to vet with a reloaded list.
 func main() {
 	opts, err := parseOpts()
 	if err != nil {
@@ -47,20 +56,46 @@ func main() {
 	vetBot := NewVetBot(opts.GithubToken, opts)
 	defer vetBot.Close()
 
-	issueReporter, err := NewIssueReporter(&vetBot, opts.TargetOwner, opts.TargetRepo)
+	sink, err := NewFindingSink(&vetBot, opts)
+	if err != nil {
+		log.Fatalf("can't create finding sink: %v", err)
+	}
+	defer func() {
+		if err := sink.Close(); err != nil {
+			log.Printf("error closing finding sink: %v", err)
+		}
+	}()
+
+	issueReporter, err := NewIssueReporter(&vetBot, sink)
 
 	if err != nil {
 		log.Fatalf("can't start issue reporter: %v", err)
 	}
 
 	if opts.AcceptListPath != "" {
-		err := acceptlist.LoadAcceptList(opts.AcceptListPath)
+		vetBot.acceptList, err = acceptlist.NewReloader(opts.AcceptListPath)
 		if err != nil {
 			log.Fatalf("cannot read accept list: %v", err)
 		}
 	}
 
-	if opts.SingleRepo == "" {
+	if opts.ClassifyPath != "" {
+		vetBot.classifier, err = classify.FromFile(opts.ClassifyPath)
+		if err != nil {
+			log.Fatalf("cannot read classification rules: %v", err)
+		}
+	}
+
+	if opts.SummaryPath != "" {
+		err := summary.Load(opts.SummaryPath)
+		if err != nil {
+			log.Fatalf("cannot read summary: %v", err)
+		}
+	}
+
+	if opts.LocalPath != "" {
+		vetLocalPath(&vetBot, issueReporter)
+	} else if opts.SingleRepo == "" {
 		sampler, err := NewRepositorySampler(vetBot.db)
 		if err != nil {
 			log.Fatalf("can't start sampler: %v", err)
@@ -75,12 +110,19 @@ func sampleRepos(vetBot *VetBot, sampler *RepositorySampler, issueReporter *Issu
 	log.Println("entering repository sampling loop")
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
 	for {
 		select {
 		case <-interrupt:
 			vetBot.wg.Wait()
 			return
+		case <-hangup:
+			if vetBot.acceptList != nil {
+				vetBot.acceptList.Request()
+			}
 		default:
+			vetBot.reloadAcceptList()
 			err := sampler.Sample(func(r Repository) error {
 				return VetRepositoryBulk(vetBot, issueReporter, r)
 			})
@@ -106,6 +148,32 @@ func sampleRepo(vetBot *VetBot, issueReporter *IssueReporter) {
 	vetBot.wg.Wait()
 }
 
+// vetLocalPath vets the local directory or tarball found at the configured path. Findings are reported as if
+// they came from the repository passed via -read-single, if any.
+func vetLocalPath(vetBot *VetBot, issueReporter *IssueReporter) {
+	src, err := NewLocalSource(vetBot.opts.LocalPath)
+	if err != nil {
+		log.Printf("error: %v", err)
+		return
+	}
+	repo := Repository{
+		Owner: vetBot.opts.SingleOwner,
+		Repo:  vetBot.opts.SingleRepo,
+	}
+	if repo.Repo == "" {
+		abs, err := filepath.Abs(vetBot.opts.LocalPath)
+		if err != nil {
+			abs = vetBot.opts.LocalPath
+		}
+		repo = Repository{Owner: "local", Repo: filepath.Base(abs)}
+	}
+	err = VetSource(vetBot, issueReporter, src, repo, "")
+	if err != nil {
+		log.Printf("error: %v", err)
+	}
+	vetBot.wg.Wait()
+}
+
 // VetBot wraps the GitHub client and context used for all GitHub API requests.
 type VetBot struct {
 	client      *ratelimit.Client
@@ -114,6 +182,19 @@ type VetBot struct {
 	db          *sql.DB
 	statsFile   *MutexWriter
 	statsWriter *csv.Writer
+	acceptList  *acceptlist.Reloader
+	classifier  *classify.Classifier
+}
+
+// reloadAcceptList reloads the accept list if its file has changed or a reload was requested via SIGHUP. It is only
+// called by sampleRepos, between repositories, so that every repository is vetted using a single accept list.
+func (vb *VetBot) reloadAcceptList() {
+	if vb.acceptList == nil {
+		return
+	}
+	if _, err := vb.acceptList.MaybeReload(); err != nil {
+		log.Printf("cannot reload accept list; keeping the current list: %v", err)
+	}
 }
 
 // NewVetBot creates a new bot using the provided GitHub token for access.
@@ -161,6 +242,7 @@ func NewVetBot(token string, opts opts) VetBot {
 		opts:        opts,
 		statsFile:   &mw,
 		statsWriter: csv.NewWriter(&mw),
+		classifier:  classify.Default(),
 	}
 }
 
@@ -13,12 +13,14 @@ import (
 	"golang.org/x/tools/go/ast/inspector"
 )
 
-// Analyzer provides a set of function signatures whose invocations start a goroutine.
-// False-positives should be expected, as no type-checking information is used during the
-// analysis, which relies only on approximate knowledge of the call-graph.
+// Analyzer provides a set of function signatures whose invocations start a goroutine, along with the indices of the
+// function arguments each of them may run in a goroutine. A function argument may be run in a goroutine if it is
+// mentioned by a go statement in the declaration, or if it is passed to a function argument of another declaration
+// which may be run in a goroutine. False-positives should be expected, as no type-checking information is used
+// during the analysis, which relies only on approximate knowledge of the call-graph.
 var Analyzer = &analysis.Analyzer{
 	Name:             "nogofunc",
-	Doc:              "gathers a list of function signatures whose invocations may pass a pointer to a function that starts a goroutine",
+	Doc:              "gathers a list of function signatures whose invocations may pass a pointer or a function to a function that starts a goroutine",
 	Run:              run,
 	RunDespiteErrors: true,
 	Requires:         []*analysis.Analyzer{inspect.Analyzer, packid.Analyzer, callgraph.Analyzer},
@@ -28,10 +30,17 @@ var Analyzer = &analysis.Analyzer{
 // Result is the result of the nogofunc analyzer.
 type Result struct {
 	// AsyncSignatures is a set of signatures from which a goroutine can be reached in the callgraph
-	// via functions that accept pointer arguments.
+	// via functions that accept pointer or function arguments.
 	AsyncSignatures map[callgraph.Signature]struct{}
 	// ContainsGoStmt is a set of signatures whose declarations contain a go statement.
 	ContainsGoStmt map[callgraph.Signature]struct{}
+	// AsyncFuncArgs maps function signatures to the indices of the function arguments they may run in a goroutine.
+	AsyncFuncArgs map[callgraph.Signature][]int
+	// StartsFuncArg is a set of signatures whose declarations mention a function argument in a go statement.
+	StartsFuncArg map[callgraph.Signature]struct{}
+	// FuncArgGraph is the subgraph of the callgraph through which function arguments are passed on to a declaration
+	// in StartsFuncArg.
+	FuncArgGraph callgraph.CallGraph
 }
 
 type signatureFacts struct {
@@ -71,11 +80,141 @@ func run(pass *analysis.Pass) (interface{}, error) {
 	})
 
 	result.ContainsGoStmt, result.AsyncSignatures = findAsyncSignatures(sigByPos, graph.ApproxCallGraph)
+	result.findAsyncFuncArgs(inspect, graph)
 	return &result, nil
 }
 
+// funcArgs maps the source position of the declaration of each function argument of a function declaration to its
+// positional index.
+type funcArgs map[token.Pos]int
+
+// parseFuncArgs finds the function arguments of the provided declaration.
+func parseFuncArgs(graph *callgraph.Result, fdec *ast.FuncDecl) funcArgs {
+	result := make(funcArgs)
+	posIdx := 0
+	if fdec.Type.Params != nil {
+		for _, field := range fdec.Type.Params.List {
+			if !graph.IsFuncType(field.Type) {
+				posIdx += len(field.Names)
+				continue
+			}
+			for _, name := range field.Names {
+				result[name.Obj.Pos()] = posIdx
+				posIdx++
+			}
+		}
+	}
+	return result
+}
+
+// mentionedBy returns the indices of every function argument mentioned anywhere in the provided node.
+func (fa funcArgs) mentionedBy(node ast.Node) []int {
+	var result []int
+	ast.Inspect(node, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Obj == nil {
+			return true
+		}
+		if idx, ok := fa[id.Obj.Pos()]; ok && !contains(result, idx) {
+			result = append(result, idx)
+		}
+		return true
+	})
+	return result
+}
+
+// argFlow records that a function argument of the caller is passed to the callee as the argument at calleeIdx.
+type argFlow struct {
+	caller    callgraph.Signature
+	callerIdx int
+	callee    callgraph.Signature
+	calleeIdx int
+}
+
+// findAsyncFuncArgs finds the function arguments of each declaration which are mentioned by a go statement, and
+// follows them back through every call which passes a function argument of the caller in their place.
+func (r *Result) findAsyncFuncArgs(inspect *inspector.Inspector, graph *callgraph.Result) {
+	r.AsyncFuncArgs = make(map[callgraph.Signature][]int)
+	r.StartsFuncArg = make(map[callgraph.Signature]struct{})
+	r.FuncArgGraph = callgraph.NewCallGraph()
+
+	nodeFilter := []ast.Node{
+		(*ast.FuncDecl)(nil),
+		(*ast.GoStmt)(nil),
+		(*ast.CallExpr)(nil),
+	}
+	args := make(map[token.Pos]funcArgs)
+	var flows []argFlow
+	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
+		if fdec, ok := n.(*ast.FuncDecl); ok {
+			args[fdec.Pos()] = parseFuncArgs(graph, fdec)
+			return true
+		}
+		fdec := outermostFuncDecl(stack)
+		if fdec == nil || len(args[fdec.Pos()]) == 0 {
+			return true
+		}
+		sig := graph.DeclSignature(fdec)
+		switch typed := n.(type) {
+		case *ast.GoStmt: // a function argument mentioned anywhere in a go statement may be run by the goroutine.
+			for _, idx := range args[fdec.Pos()].mentionedBy(typed.Call) {
+				r.StartsFuncArg[sig] = struct{}{}
+				r.markAsync(sig, idx)
+			}
+		case *ast.CallExpr:
+			callSig := graph.CallSignature(typed)
+			if !graph.Declares(callSig) {
+				return true
+			}
+			for calleeIdx, arg := range typed.Args {
+				for _, idx := range args[fdec.Pos()].mentionedBy(arg) {
+					flows = append(flows, argFlow{caller: sig, callerIdx: idx, callee: callSig, calleeIdx: calleeIdx})
+				}
+			}
+		}
+		return true
+	})
+
+	// carry function arguments which may be run in a goroutine back through their callers, until no more are found.
+	// Calls which could only be resolved by name and arity may run any argument run by a declaration they refer to.
+	for changed := true; changed; {
+		changed = false
+		for coarse, aliases := range graph.Aliases {
+			for _, alias := range aliases {
+				if len(r.AsyncFuncArgs[alias]) == 0 {
+					continue
+				}
+				r.FuncArgGraph.AddCall(r.FuncArgGraph.AddSignature(coarse), r.FuncArgGraph.AddSignature(alias))
+				for _, idx := range r.AsyncFuncArgs[alias] {
+					changed = r.markAsync(coarse, idx) || changed
+				}
+			}
+		}
+		for _, flow := range flows {
+			if !contains(r.AsyncFuncArgs[flow.callee], flow.calleeIdx) {
+				continue
+			}
+			r.FuncArgGraph.AddCall(r.FuncArgGraph.AddSignature(flow.caller), r.FuncArgGraph.AddSignature(flow.callee))
+			changed = r.markAsync(flow.caller, flow.callerIdx) || changed
+		}
+	}
+}
+
+// markAsync marks the function argument at the provided index of the provided signature as one which may be run in
+// a goroutine. It returns true only if the argument was not marked already.
+func (r *Result) markAsync(sig callgraph.Signature, idx int) bool {
+	if contains(r.AsyncFuncArgs[sig], idx) {
+		return false
+	}
+	r.AsyncFuncArgs[sig] = append(r.AsyncFuncArgs[sig], idx)
+	return true
+}
+
 // findAsyncSignatures finds a list of Signatures for functions which eventually call a goroutine via some path
-// of functions in the callgraph that can pass pointer arguments.
+// of functions in the callgraph that can pass pointer or function arguments.
 func findAsyncSignatures(sigs map[token.Pos]*signatureFacts, graph *callgraph.CallGraph) (map[callgraph.Signature]struct{}, map[callgraph.Signature]struct{}) {
 	var toCheck []callgraph.Signature
 	startsGoroutine := make(map[callgraph.Signature]struct{})
@@ -108,3 +247,12 @@ func outermostFuncDecl(stack []ast.Node) *ast.FuncDecl {
 	}
 	return nil
 }
+
+func contains(arr []int, v int) bool {
+	for _, x := range arr {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
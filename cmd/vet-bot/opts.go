@@ -15,10 +15,18 @@ type opts struct {
 	TargetRepo        string
 	SingleOwner       string
 	SingleRepo        string
+	LocalPath         string
 	AcceptListPath    string
+	SummaryPath       string
 	DbBootstrapFolder string
 	ReposFile         string
 	DatabaseFile      string
+	Sink              string
+	SarifFile         string
+	SarifPerRepo      bool
+	TypeCheck         bool
+	Fix               bool
+	ClassifyPath      string
 }
 
 // OptSchema defines a configuration option which can come either from the command-line or
@@ -41,7 +49,7 @@ type OptSchema struct {
 }
 
 var optSchemas []OptSchema = []OptSchema{
-	{"GITHUB_TOKEN", "token", "GitHub access token", "", true,
+	{"GITHUB_TOKEN", "token", "GitHub access token; required unless a local path is vetted using a sink other than 'github'", "", false,
 		func(o *opts, value string) error { o.GithubToken = value; return nil }, ""},
 	{"SCHEMA_FOLDER", "schemas", "directory containing SQL schemas", "", false,
 		func(o *opts, value string) error { o.DbBootstrapFolder = value; return nil }, ""},
@@ -51,6 +59,8 @@ var optSchemas []OptSchema = []OptSchema{
 		func(o *opts, value string) error { o.ReposFile = value; return nil }, ""},
 	{"ACCEPT_LIST_FILE", "accept", "path to accept list YAML file", "", false,
 		func(o *opts, value string) error { o.AcceptListPath = value; return nil }, ""},
+	{"CLASSIFY_FILE", "classify", "path to YAML file of rules used to classify files as generated, vendored, test, example or normal code", "", false,
+		func(o *opts, value string) error { o.ClassifyPath = value; return nil }, ""},
 	{"DATABASE_FILE", "db", "path to database sqlite3 file", "", false,
 		func(o *opts, value string) error { o.DatabaseFile = value; return nil }, ""},
 	{"REPO_TO_READ", "read-single", "owner/repository of single repository to read", "", false,
@@ -58,6 +68,18 @@ var optSchemas []OptSchema = []OptSchema{
 			o.SingleOwner, o.SingleRepo = parseRepoString(value, "single")
 			return nil
 		}, ""},
+	{"LOCAL_PATH", "path", "path to a local directory or .tar.gz file to read instead of downloading from GitHub", "", false,
+		func(o *opts, value string) error { o.LocalPath = value; return nil }, ""},
+	{"FINDING_SINK", "sink", "where findings are reported; one of 'github', 'db', 'jsonl' or 'sarif'", SinkGithub, false,
+		func(o *opts, value string) error { o.Sink = value; return nil }, ""},
+	{"SARIF_FILE", "sarif", "path to SARIF file written when the sarif sink is used", "findings.sarif", false,
+		func(o *opts, value string) error { o.SarifFile = value; return nil }, ""},
+	{"SARIF_PER_REPO", "sarif-per-repo", "if 'true', -sarif names a directory into which one SARIF file is written per repository", "false", false,
+		func(o *opts, value string) error { o.SarifPerRepo = value == "true"; return nil }, ""},
+	{"TYPE_CHECK", "typecheck", "if 'true', run the type-checker and use type information for each package which type-checks cleanly", "false", false,
+		func(o *opts, value string) error { o.TypeCheck = value == "true"; return nil }, ""},
+	{"APPLY_FIXES", "fix", "if 'true', apply the fixes suggested for each finding to the files in the directory passed via -path", "false", false,
+		func(o *opts, value string) error { o.Fix = value == "true"; return nil }, ""},
 	{"GITHUB_REPO", "repo", "owner/repository of GitHub repo where issues will be filed", "kalexmills/rangeloop-test-repo", false,
 		func(o *opts, value string) error {
 			o.TargetOwner, o.TargetRepo = parseRepoString(value, "repo")
@@ -90,9 +112,18 @@ func parseOpts() (opts, error) {
 		}
 		schema.OptSetter(&result, schema.DefaultValue)
 	}
+	if result.GithubToken == "" && result.usesGithub() {
+		return opts{}, fmt.Errorf("no configured value for option 'GITHUB_TOKEN', which is required unless a local path is vetted using a sink other than '%s'", SinkGithub)
+	}
 	return result, nil
 }
 
+// usesGithub returns true if either the source of the code vetted or the sink which findings are reported to
+// calls the GitHub API.
+func (o opts) usesGithub() bool {
+	return o.LocalPath == "" || o.Sink == SinkGithub
+}
+
 func parseRepoString(str string, flag string) (string, string) {
 	repoToks := strings.Split(str, "/")
 	if len(repoToks) != 2 {
@@ -4,9 +4,18 @@ import (
 	"errors"
 	"go/ast"
 	"go/token"
+	"go/types"
+	"path"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
+	"github.com/github-vet/bots/cmd/vet-bot/stats"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
@@ -17,49 +26,159 @@ var Analyzer = &analysis.Analyzer{
 	Doc:              "simple package resolution to match 3rd-party functions against a list of safe calls",
 	Run:              run,
 	RunDespiteErrors: true,
-	Requires:         []*analysis.Analyzer{inspect.Analyzer},
+	Requires:         []*analysis.Analyzer{inspect.Analyzer, gomod.Analyzer},
 	ResultType:       reflect.TypeOf((*PackageResolver)(nil)),
 }
 
 func run(pass *analysis.Pass) (interface{}, error) {
 	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	modules := pass.ResultOf[gomod.Analyzer].(*gomod.Result)
 
 	nodeFilter := []ast.Node{
 		(*ast.ImportSpec)(nil),
 	}
 
 	packages := &PackageResolver{
-		importsByFile: make(map[token.Pos]map[string]string),
+		importsByFile:    make(map[token.Pos]map[string]string),
+		dotImportsByFile: make(map[token.Pos][]string),
+		ambiguousByFile:  make(map[token.Pos]map[string]struct{}),
+		info:             pass.TypesInfo,
 	}
+	names := packageNames(pass)
 
 	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
+		if !push {
+			return true
+		}
 		spec := n.(*ast.ImportSpec)
 		if spec.Path == nil {
 			return true // malformed import
 		}
+		packagePath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return true // malformed import
+		}
+		file := outermostFile(stack)
 
 		var ident string
-		packagePath := strings.ReplaceAll(spec.Path.Value, `"`, "")
-		if spec.Name != nil {
-			ident = strings.ReplaceAll(spec.Name.Name, `"`, "")
-		} else {
-			tokens := strings.Split(packagePath, "/")
-			ident = tokens[len(tokens)-1]
+		ambiguous := false
+		switch {
+		case spec.Name == nil:
+			ident, ambiguous = importedName(packagePath, candidateDirs(modules.ModuleFor(file.Pos()), packagePath), names)
+		case spec.Name.Name == "_":
+			return true // blank imports can't be referred to
+		case spec.Name.Name == ".":
+			packages.dotImportsByFile[file.Pos()] = append(packages.dotImportsByFile[file.Pos()], packagePath)
+			return true
+		default:
+			ident = spec.Name.Name
 		}
 
-		file := outermostFile(stack)
 		if _, ok := packages.importsByFile[file.Pos()]; !ok {
 			packages.importsByFile[file.Pos()] = make(map[string]string)
 		}
+		if prev, ok := packages.importsByFile[file.Pos()][ident]; ok && prev != packagePath {
+			ambiguous = true // two imports were assumed to have the same name
+		}
 		packages.importsByFile[file.Pos()][ident] = packagePath
+		if ambiguous {
+			stats.AddCount(stats.StatPackidAmbiguousImports, 1)
+			if _, ok := packages.ambiguousByFile[file.Pos()]; !ok {
+				packages.ambiguousByFile[file.Pos()] = make(map[string]struct{})
+			}
+			packages.ambiguousByFile[file.Pos()][ident] = struct{}{}
+		}
 		return true
 	})
 
 	return packages, nil
 }
 
+// packageNames finds the names declared in the package clauses of the files found in each directory, ignoring
+// external test packages.
+func packageNames(pass *analysis.Pass) map[string]map[string]struct{} {
+	result := make(map[string]map[string]struct{})
+	for _, file := range pass.Files {
+		if file.Name == nil || strings.HasSuffix(file.Name.Name, "_test") {
+			continue
+		}
+		dir := path.Dir(filepath.ToSlash(pass.Fset.File(file.Pos()).Name()))
+		if _, ok := result[dir]; !ok {
+			result[dir] = make(map[string]struct{})
+		}
+		result[dir][file.Name.Name] = struct{}{}
+	}
+	return result
+}
+
+// candidateDirs returns the directories in which the source of the package imported via the provided path may be
+// found, if it belongs to or is vendored by the provided module.
+func candidateDirs(mod *gomod.Module, importPath string) []string {
+	if mod == nil {
+		return nil
+	}
+	var result []string
+	if dir, ok := mod.DirFor(importPath); ok {
+		result = append(result, dir)
+	}
+	return append(result, path.Join(mod.Dir, "vendor", importPath))
+}
+
+// importedName returns the name of the package imported via the provided path. The name is read from the package
+// clause of the files found in the provided directories whenever there are any; otherwise, it is assumed from the
+// import path in the same way as goimports. ambiguous is true whenever the name returned may be wrong; either
+// because the package clauses of the files found disagree, or because the name assumed differs from the last
+// element of the import path.
+func importedName(importPath string, dirs []string, names map[string]map[string]struct{}) (name string, ambiguous bool) {
+	for _, dir := range dirs {
+		clauses, ok := names[dir]
+		if !ok {
+			continue
+		}
+		var sorted []string
+		for clause := range clauses {
+			sorted = append(sorted, clause)
+		}
+		sort.Strings(sorted)
+		return sorted[0], len(sorted) > 1
+	}
+	name = AssumedName(importPath)
+	return name, name != path.Base(importPath)
+}
+
+// AssumedName returns the name assumed for the package imported via the provided import path, when its package
+// clause can't be read. Like goimports, it uses the last element of the path, skipping major version suffixes such
+// as "/v2", trimming any "go-" prefix, and dropping everything from the first character which isn't allowed in an
+// identifier, such as the ".v2" in "gopkg.in/yaml.v2".
+func AssumedName(importPath string) string {
+	base := path.Base(importPath)
+	if strings.HasPrefix(base, "v") {
+		if _, err := strconv.Atoi(base[1:]); err == nil {
+			if dir := path.Dir(importPath); dir != "." {
+				base = path.Base(dir)
+			}
+		}
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, notIdentifier); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+func notIdentifier(ch rune) bool {
+	return !('a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || ch == '_' ||
+		ch >= utf8.RuneSelf && (unicode.IsLetter(ch) || unicode.IsDigit(ch)))
+}
+
+// PackageResolver resolves the package referred to by the identifiers used in selector expressions. If type
+// information is available, it is used to resolve identifiers exactly; otherwise, identifiers are matched against
+// the last element of each imported package path.
 type PackageResolver struct {
-	importsByFile map[token.Pos]map[string]string
+	importsByFile    map[token.Pos]map[string]string
+	dotImportsByFile map[token.Pos][]string            // the paths of packages imported with a dot by each file
+	ambiguousByFile  map[token.Pos]map[string]struct{} // identifiers whose import may have been guessed wrong
+	info             *types.Info
 }
 
 var errNotPackageCall error = errors.New("not a package call")
@@ -76,6 +195,16 @@ func (pr *PackageResolver) PackageFor(callExpr *ast.CallExpr, stack []ast.Node)
 	if !ok {
 		return "", errNotPackageCall
 	}
+	if pr.info != nil {
+		if pkgName, ok := pr.info.Uses[x].(*types.PkgName); ok {
+			return pkgName.Imported().Path(), nil
+		}
+	}
+	return pr.ImportPath(x.Name, stack)
+}
+
+// ImportPath retrieves the path of the package imported under the provided name by the file found in the stack.
+func (pr *PackageResolver) ImportPath(name string, stack []ast.Node) (string, error) {
 	file := outermostFile(stack)
 	if file == nil {
 		return "", errNotPackageCall
@@ -84,13 +213,246 @@ func (pr *PackageResolver) PackageFor(callExpr *ast.CallExpr, stack []ast.Node)
 	if !ok {
 		return "", errNotPackageCall
 	}
-	path, ok := fileImports[x.Name]
+	path, ok := fileImports[name]
 	if !ok {
 		return "", errNotPackageCall
 	}
 	return path, nil
 }
 
+// ReceiverPackageFor retrieves the path of the package which declares the type of the receiver of the provided
+// method call. Without type information, the type of the receiver is inferred from the declaration of the variable
+// it names; receivers declared with an explicit type or initialized by a composite literal, a call to new, or a call
+// into a third-party package (such as errgroup.WithContext) are all resolved.
+func (pr *PackageResolver) ReceiverPackageFor(callExpr *ast.CallExpr, stack []ast.Node) (string, error) {
+	path, _, err := pr.ReceiverTypeFor(callExpr, stack)
+	return path, err
+}
+
+// ReceiverTypeFor retrieves the path of the package which declares the type of the receiver of the provided method
+// call, along with the name of the type, in the same way as ReceiverPackageFor. The name of the type is empty when
+// only its package is known; when a receiver is initialized by a call into a third-party package, the type is only
+// known if the function called is a constructor whose name is the name of the type prefixed by "New", such as
+// bytes.NewBuffer, or a well-known function whose result type is listed in knownConstructors, such as
+// errgroup.WithContext.
+func (pr *PackageResolver) ReceiverTypeFor(callExpr *ast.CallExpr, stack []ast.Node) (path string, typeName string, err error) {
+	selExp, ok := callExpr.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", "", errNotPackageCall
+	}
+	x, ok := selExp.X.(*ast.Ident)
+	if !ok {
+		return "", "", errNotPackageCall
+	}
+	if pr.info != nil {
+		if path, typeName := typePackage(pr.info.TypeOf(x)); path != "" {
+			return path, typeName, nil
+		}
+	}
+	if x.Obj == nil || x.Obj.Kind != ast.Var {
+		return "", "", errNotPackageCall
+	}
+	file := outermostFile(stack)
+	if file == nil {
+		return "", "", errNotPackageCall
+	}
+	fileImports, ok := pr.importsByFile[file.Pos()]
+	if !ok {
+		return "", "", errNotPackageCall
+	}
+	expr := declaringExpr(x)
+	called := false // true if the type is found from the result of a function call
+	for expr != nil {
+		switch typed := expr.(type) {
+		case *ast.ParenExpr:
+			expr = typed.X
+		case *ast.StarExpr:
+			expr = typed.X
+		case *ast.UnaryExpr:
+			expr = typed.X
+		case *ast.CompositeLit:
+			expr = typed.Type
+		case *ast.CallExpr:
+			if id, ok := typed.Fun.(*ast.Ident); ok && id.Name == "new" && len(typed.Args) == 1 {
+				expr = typed.Args[0]
+			} else {
+				expr = typed.Fun
+				called = true
+			}
+		case *ast.SelectorExpr:
+			pkg, ok := typed.X.(*ast.Ident)
+			if !ok || pkg.Obj != nil { // identifiers which refer to packages are never resolved by the parser
+				return "", "", errNotPackageCall
+			}
+			path, ok := fileImports[pkg.Name]
+			if !ok {
+				return "", "", errNotPackageCall
+			}
+			if called {
+				return path, constructedType(path, typed.Sel.Name), nil
+			}
+			return path, typed.Sel.Name, nil
+		default:
+			return "", "", errNotPackageCall
+		}
+	}
+	return "", "", errNotPackageCall
+}
+
+// QualifiedCallee retrieves the path of the package declaring the function called by the provided call expression,
+// along with the name of the function. Methods are named by the name of their receiver's type and the name of the
+// method, separated by a dot, such as "Buffer.WriteString"; method calls whose receiver's type can't be found are
+// not resolved.
+func (pr *PackageResolver) QualifiedCallee(callExpr *ast.CallExpr, stack []ast.Node) (path string, name string, err error) {
+	if id, ok := callExpr.Fun.(*ast.Ident); ok {
+		path, err := pr.dotImportFor(id, stack)
+		return path, id.Name, err
+	}
+	selExp, ok := callExpr.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", "", errNotPackageCall
+	}
+	if path, err := pr.PackageFor(callExpr, stack); err == nil {
+		return path, selExp.Sel.Name, nil
+	}
+	path, typeName, err := pr.ReceiverTypeFor(callExpr, stack)
+	if err != nil {
+		return "", "", err
+	}
+	if typeName == "" {
+		return "", "", errNotPackageCall
+	}
+	return path, typeName + "." + selExp.Sel.Name, nil
+}
+
+// dotImportFor retrieves the path of the package which declares the function named by the provided identifier, if
+// it was imported with a dot. Without type information, identifiers which aren't declared in the same file are
+// resolved only if the file has exactly one dot import.
+func (pr *PackageResolver) dotImportFor(id *ast.Ident, stack []ast.Node) (string, error) {
+	if pr.info != nil {
+		if fn, ok := pr.info.Uses[id].(*types.Func); ok && fn.Pkg() != nil {
+			return fn.Pkg().Path(), nil
+		}
+	}
+	if id.Obj != nil {
+		return "", errNotPackageCall
+	}
+	file := outermostFile(stack)
+	if file == nil {
+		return "", errNotPackageCall
+	}
+	dotImports := pr.dotImportsByFile[file.Pos()]
+	if len(dotImports) != 1 {
+		return "", errNotPackageCall
+	}
+	return dotImports[0], nil
+}
+
+// AmbiguousCallee returns true if the package of the function called by the provided call expression was resolved
+// from a guess which may be wrong, such as a package name assumed from its import path, or a function assumed to
+// come from a dot import.
+func (pr *PackageResolver) AmbiguousCallee(callExpr *ast.CallExpr, stack []ast.Node) bool {
+	file := outermostFile(stack)
+	if file == nil {
+		return false
+	}
+	switch fun := callExpr.Fun.(type) {
+	case *ast.Ident:
+		if pr.info != nil && pr.info.Uses[fun] != nil {
+			return false
+		}
+		_, err := pr.dotImportFor(fun, stack)
+		return err == nil
+	case *ast.SelectorExpr:
+		x, ok := fun.X.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		if pr.info != nil {
+			if _, ok := pr.info.Uses[x].(*types.PkgName); ok {
+				return false
+			}
+		}
+		_, ok = pr.ambiguousByFile[file.Pos()][x.Name]
+		return ok
+	}
+	return false
+}
+
+// knownConstructors maps the path of well-known packages to the functions they declare whose first result is a type
+// which can't be found from the name of the function, and to the name of that type.
+var knownConstructors = map[string]map[string]string{
+	"bytes":                      {"NewBufferString": "Buffer"},
+	"golang.org/x/sync/errgroup": {"WithContext": "Group"},
+	"net/http":                   {"NewRequestWithContext": "Request"},
+	"os":                         {"Create": "File", "Open": "File", "OpenFile": "File"},
+	"strings":                    {"NewReplacer": "Replacer"},
+}
+
+// constructedType returns the name of the type constructed by the function with the provided name, declared in the
+// package with the provided path. Functions are looked up in knownConstructors first; otherwise, the type is only
+// found if the function follows the convention of naming constructors after their type, prefixed by "New".
+func constructedType(path, funcName string) string {
+	if typeName, ok := knownConstructors[path][funcName]; ok {
+		return typeName
+	}
+	typeName := strings.TrimPrefix(funcName, "New")
+	if typeName == funcName || typeName == "" || !ast.IsExported(typeName) {
+		return ""
+	}
+	return typeName
+}
+
+// declaringExpr returns the expression which determines the type of the variable named by the provided identifier;
+// either its declared type or the value it is initialized with.
+func declaringExpr(x *ast.Ident) ast.Expr {
+	switch decl := x.Obj.Decl.(type) {
+	case *ast.Field:
+		return decl.Type
+	case *ast.ValueSpec:
+		if decl.Type != nil {
+			return decl.Type
+		}
+		for i, name := range decl.Names {
+			if name.Name != x.Name {
+				continue
+			}
+			if len(decl.Values) == len(decl.Names) {
+				return decl.Values[i]
+			}
+			if len(decl.Values) == 1 {
+				return decl.Values[0]
+			}
+		}
+	case *ast.AssignStmt:
+		for i, lhs := range decl.Lhs {
+			if id, ok := lhs.(*ast.Ident); !ok || id.Name != x.Name {
+				continue
+			}
+			if len(decl.Rhs) == len(decl.Lhs) {
+				return decl.Rhs[i]
+			}
+			if len(decl.Rhs) == 1 {
+				return decl.Rhs[0]
+			}
+		}
+	}
+	return nil
+}
+
+// typePackage returns the path of the package declaring the provided named type or pointer to a named type, along
+// with the name of the type.
+func typePackage(t types.Type) (string, string) {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return "", ""
+	}
+	return named.Obj().Pkg().Path(), named.Obj().Name()
+}
+
 func outermostFile(stack []ast.Node) *ast.File {
 	for i := 0; i < len(stack); i++ {
 		if typed, ok := stack[i].(*ast.File); ok {
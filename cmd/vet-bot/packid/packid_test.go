@@ -2,9 +2,17 @@ package packid_test
 
 import (
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"testing"
 
+	"github.com/github-vet/bots/cmd/vet-bot/driver"
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
 	"github.com/github-vet/bots/cmd/vet-bot/packid"
+	"github.com/github-vet/bots/cmd/vet-bot/stats"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/analysistest"
 )
 
@@ -13,3 +21,114 @@ func Test(t *testing.T) {
 	result := analysistest.Run(t, testdata, packid.Analyzer, "a")
 	fmt.Println(result[0].Result)
 }
+
+func TestQualifiedCallee(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, packid.Analyzer, "receivers")
+	pass := results[0].Pass
+	expected := []string{
+		"sync.WaitGroup.Wait",
+		"bytes.Buffer.WriteString",
+		"strings.Builder.WriteString",
+		"bytes.Buffer.WriteString",
+		"bytes.NewBufferString",
+		"bytes.Buffer.WriteString",
+		"strings.NewReader",
+		"strings.Reader.Len",
+		"database/sql.DB.QueryRow",
+		"bytes.Contains",
+	}
+	assert.Equal(t, expected, qualifiedCallees(results[0].Result.(*packid.PackageResolver), pass.Files[0]))
+
+	// without type information, bytes.NewBufferString is known to construct a bytes.Buffer.
+	outcomes, err := driver.Run(pass.Fset, pass.Files, nil, []*analysis.Analyzer{packid.Analyzer}, func(analysis.Diagnostic) {})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, qualifiedCallees(outcomes[len(outcomes)-1].Result.(*packid.PackageResolver), pass.Files[0]))
+}
+
+func qualifiedCallees(pr *packid.PackageResolver, file *ast.File) []string {
+	var callees []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if path, name, err := pr.QualifiedCallee(call, []ast.Node{file}); err == nil {
+				callees = append(callees, path+"."+name)
+			}
+		}
+		return true
+	})
+	return callees
+}
+
+func TestAssumedName(t *testing.T) {
+	tests := map[string]string{
+		"fmt":                                    "fmt",
+		"net/http":                               "http",
+		"gopkg.in/yaml.v2":                       "yaml",
+		"github.com/google/go-github/v32/github": "github",
+		"github.com/panjf2000/ants/v2":           "ants",
+		"github.com/mattn/go-sqlite3":            "sqlite3",
+		"github.com/owner/go-repo.go":            "repo",
+	}
+	for importPath, expected := range tests {
+		assert.Equal(t, expected, packid.AssumedName(importPath), importPath)
+	}
+}
+
+func TestImportedNames(t *testing.T) {
+	stats.Clear()
+
+	sources := map[string]string{
+		"repo/main.go": `package main
+
+import (
+	_ "embed"
+	. "strings"
+
+	"example.com/repo/lib/go-thing"
+	"github.com/owner/proj/v2"
+	"gopkg.in/yaml.v2"
+)
+
+func main() {
+	thing2.Do()
+	yaml.Unmarshal(nil, nil)
+	proj.Run()
+	ToUpper("x")
+}
+`,
+		"repo/lib/go-thing/thing.go": "package thing2\n\nfunc Do() {}\n",
+	}
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range []string{"repo/main.go", "repo/lib/go-thing/thing.go"} {
+		file, err := parser.ParseFile(fset, name, sources[name], 0)
+		assert.NoError(t, err)
+		files = append(files, file)
+	}
+	inputs := map[*analysis.Analyzer]interface{}{
+		gomod.Analyzer: gomod.Analyze(fset, files, gomod.Files(map[string][]byte{"repo/go.mod": []byte("module example.com/repo\n")})),
+	}
+	outcomes, err := driver.RunWithInputs(fset, files, nil, inputs, []*analysis.Analyzer{packid.Analyzer}, func(analysis.Diagnostic) {})
+	assert.NoError(t, err)
+	pr := outcomes[len(outcomes)-1].Result.(*packid.PackageResolver)
+
+	var callees []string
+	var ambiguous []bool
+	ast.Inspect(files[0], func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			path, name, err := pr.QualifiedCallee(call, []ast.Node{files[0]})
+			assert.NoError(t, err)
+			callees = append(callees, path+"."+name)
+			ambiguous = append(ambiguous, pr.AmbiguousCallee(call, []ast.Node{files[0]}))
+		}
+		return true
+	})
+	assert.Equal(t, []string{
+		"example.com/repo/lib/go-thing.Do",
+		"gopkg.in/yaml.v2.Unmarshal",
+		"github.com/owner/proj/v2.Run",
+		"strings.ToUpper",
+	}, callees)
+	assert.Equal(t, []bool{false, true, true, true}, ambiguous)
+	assert.EqualValues(t, 2, stats.GetCount(stats.StatPackidAmbiguousImports))
+}
@@ -0,0 +1,32 @@
+package receivers
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"sync"
+)
+
+func receivers(db *sql.DB) {
+	var wg sync.WaitGroup
+	wg.Wait()
+
+	buf := bytes.Buffer{}
+	buf.WriteString("composite literal")
+
+	ptr := &strings.Builder{}
+	ptr.WriteString("pointer to composite literal")
+
+	alloc := new(bytes.Buffer)
+	alloc.WriteString("new")
+
+	constructed := bytes.NewBufferString("constructor")
+	constructed.WriteString("constructor")
+
+	reader := strings.NewReader("constructor")
+	reader.Len()
+
+	db.QueryRow("parameter")
+
+	bytes.Contains(nil, nil)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/github-vet/bots/cmd/vet-bot/callgraph"
 	"github.com/github-vet/bots/cmd/vet-bot/packid"
 	"github.com/github-vet/bots/cmd/vet-bot/stats"
+	"github.com/github-vet/bots/cmd/vet-bot/summary"
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
@@ -17,8 +18,10 @@ import (
 
 // Analyzer gathers a list of function signatures and indices of their pointer arguments which can be proven
 // safe. A pointer argument to a function is considered safe if 1) it does not appear alone on the right-hand side
-// of any assignment statement in the function body, and 2) it does not appear alone in the body of any composite
-// literal.
+// of any assignment statement in the function body, 2) it does not appear alone in the body of any composite
+// literal, 3) it is never sent on a channel or appended to a slice, 4) it is never passed to a third-party function,
+// and 5) it is never passed to a function which returns it, in a call whose result is stored in any of these ways.
+// Third-party functions found in the accept list or the summary are trusted with the arguments they list as safe.
 var Analyzer = &analysis.Analyzer{
 	Name:             "pointerescapes",
 	Doc:              "gathers a list of function signatures and their pointer arguments which definitely do not escape during the lifetime of the function",
@@ -36,10 +39,35 @@ type Result struct {
 	DangerGraph callgraph.CallGraph
 	// WritesPtr is a set of signatures which were found to write a pointer.
 	WritesPtr map[callgraph.Signature]struct{}
+	// Escapes describes each of the ways in which the functions in WritesPtr were found to write a pointer.
+	Escapes map[callgraph.Signature]map[EscapeKind]struct{}
 	// ThirdPartyPtrPassed is a set of signatures which were found to pass a pointer into a third-party function.
 	ThirdPartyPtrPassed map[callgraph.Signature]struct{}
+	// ReturnedPtrs maps function signatures to the indices of pointer arguments which they may return.
+	ReturnedPtrs map[callgraph.Signature][]int
 }
 
+// EscapeKind describes a way in which a pointer argument can escape the function it is passed to.
+type EscapeKind uint8
+
+const (
+	// EscapeAssign indicates a pointer argument was found alone on the right-hand side of an assignment.
+	EscapeAssign EscapeKind = iota
+	// EscapeCompositeLit indicates a pointer argument was used in a composite literal.
+	EscapeCompositeLit
+	// EscapeChanSend indicates a pointer argument was sent on a channel.
+	EscapeChanSend
+	// EscapeAppend indicates a pointer argument was appended to a slice.
+	EscapeAppend
+	// EscapeIndex indicates a pointer argument was assigned to an element of a map or a slice.
+	EscapeIndex
+	// EscapeReturn indicates a pointer argument was returned. Returned pointers only escape if the caller stores the
+	// result, in which case the escape is recorded for the caller, in whichever way the result was stored.
+	EscapeReturn
+	// EscapeClosureVar indicates a pointer argument was assigned to a variable captured by a function literal.
+	EscapeClosureVar
+)
+
 // pointerArgs is a map from the source position of the declaration of pointer arguments found in
 // function declarations to the positional index of the argument.
 type pointerArgs map[token.Pos]int
@@ -97,9 +125,14 @@ func run(pass *analysis.Pass) (interface{}, error) {
 	}
 
 	callsBySignature := callsBySignature(graph.PtrCalls)
-	safePtrArgs, writesPtr, thirdPartyPtrPassed := collectSafePtrArgs(pass, callsBySignature)
+	safePtrArgs, writesPtr, thirdPartyPtrPassed, escapes, returns := collectSafePtrArgs(pass, callsBySignature)
 	result.WritesPtr = writesPtr
+	result.Escapes = escapes
 	result.ThirdPartyPtrPassed = thirdPartyPtrPassed
+	result.ReturnedPtrs = returns.returned
+	for coarse, aliases := range graph.Aliases {
+		result.ReturnedPtrs[coarse] = returns.returnedByAny(aliases)
+	}
 
 	// handle naming collisions. We only track pointer arguments accurately when all colliding
 	// signatures share a pointer argument in the same position.
@@ -116,12 +149,29 @@ func run(pass *analysis.Pass) (interface{}, error) {
 		}
 	}
 
+	// calls which could only be resolved by name and arity are as safe as every declaration they may refer to.
+	result.mergeAliases(graph.Aliases)
+
 	// keep track of the 'danger graph' -- the subgraph of the callgraph which pass
 	// pointers directly to each other.
 	result.DangerGraph = callgraph.NewCallGraph()
 	for sig := range writesPtr {
 		result.DangerGraph.AddSignature(sig)
 	}
+	for coarse, aliases := range graph.Aliases {
+		coarseID := result.DangerGraph.AddSignature(coarse)
+		for _, alias := range aliases {
+			result.DangerGraph.AddCall(coarseID, result.DangerGraph.AddSignature(alias))
+		}
+	}
+	// callers which store or return the result of a function which returns one of their pointer arguments are
+	// connected to it, so the danger graph explains where the stored pointer came from.
+	for caller, callees := range returns.flows {
+		callerID := result.DangerGraph.AddSignature(caller)
+		for callee := range callees {
+			result.DangerGraph.AddCall(callerID, result.DangerGraph.AddSignature(callee))
+		}
+	}
 
 	// Threads the notion of an 'unsafe pointer argument' through the call-graph, by performing a breadth-first search
 	// through the called-by graph, and marking unsafe caller arguments as we visit each call-site.
@@ -129,6 +179,9 @@ func run(pass *analysis.Pass) (interface{}, error) {
 	// such in all instances.
 
 	graph.ApproxCallGraph.CalledByBFS(graph.ApproxCallGraph.CalledByRoots(), func(callSig callgraph.Signature) {
+		if aliases, ok := graph.Aliases[callSig]; ok {
+			result.SafePtrs[callSig] = intersectAll(result.SafePtrs, aliases)
+		}
 		// check all calls with a matching signature and, if they use a pointer from their caller in an
 		// unsafe position, mark the pointer argument in the caller unsafe also.
 		safeArgIndexes := result.SafePtrs[callSig]
@@ -151,112 +204,319 @@ func run(pass *analysis.Pass) (interface{}, error) {
 			}
 		}
 	})
+	result.mergeAliases(graph.Aliases)
 	return &result, nil
 }
 
-// collectSafePtrArgs parses the file and finds all function declarations which use one of their pointer
-// arguments in an assignment or a composite literal. It returns an initial safeArgMap describing the
-// pointer arguments which were found to be used safelty, along with a set of signatures that have been
-// found to write at least one of their pointer arguments.
-func collectSafePtrArgs(pass *analysis.Pass, callsBySignature map[callgraph.Signature][]callgraph.Call) (safePtrArgs safePtrArgMap, writePtrSigs map[callgraph.Signature]struct{}, thirdPartySigs map[callgraph.Signature]struct{}) {
-	graph := pass.ResultOf[callgraph.Analyzer].(*callgraph.Result)
-	// construct a set of declared functions to which a pointer is passed; any callExpr
-	// containing a pointer whose signature is not found in knownPtrSignatures must be
-	// third-party.
-	knownPtrSignatures := make(map[callgraph.Signature]struct{})
-	for _, decl := range graph.PtrSignatures {
-		knownPtrSignatures[decl.Signature] = struct{}{}
+// mergeAliases marks a pointer argument of a signature consisting of name and arity safe only if it is safe in
+// every declaration the signature may refer to.
+func (r *Result) mergeAliases(aliases map[callgraph.Signature][]callgraph.Signature) {
+	for coarse, sigs := range aliases {
+		r.SafePtrs[coarse] = intersectAll(r.SafePtrs, sigs)
+	}
+}
+
+func intersectAll(safePtrs map[callgraph.Signature][]int, sigs []callgraph.Signature) []int {
+	if len(sigs) == 0 {
+		return nil
+	}
+	result := append([]int(nil), safePtrs[sigs[0]]...)
+	for _, sig := range sigs[1:] {
+		result = intersect(result, safePtrs[sig])
 	}
+	return result
+}
 
+// collectSafePtrArgs parses the file and finds all function declarations which let one of their pointer
+// arguments escape, in any of the ways described by EscapeKind. It returns an initial safeArgMap describing the
+// pointer arguments which were found to be used safelty, along with a set of signatures that have been
+// found to write at least one of their pointer arguments, and the ways in which each of them does so. Pointer
+// arguments which are returned are tracked through the returned returnFlow, and only marked unsafe in callers which
+// store the result.
+func collectSafePtrArgs(pass *analysis.Pass, callsBySignature map[callgraph.Signature][]callgraph.Call) (safePtrArgs safePtrArgMap, writePtrSigs map[callgraph.Signature]struct{}, thirdPartySigs map[callgraph.Signature]struct{}, escapes map[callgraph.Signature]map[EscapeKind]struct{}, returns *returnFlow) {
+	graph := pass.ResultOf[callgraph.Analyzer].(*callgraph.Result)
+	// any callExpr containing a pointer whose signature is not declared in the callgraph must be third-party.
 	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
 	packageResolver := pass.ResultOf[packid.Analyzer].(*packid.PackageResolver)
 
 	safePtrArgs = newSafePtrArgMap()
 	writePtrSigs = make(map[callgraph.Signature]struct{})   // declared functions which write their pointer
 	thirdPartySigs = make(map[callgraph.Signature]struct{}) // declared functions which pass to third-party code
+	escapes = make(map[callgraph.Signature]map[EscapeKind]struct{})
+	returns = newReturnFlow(graph)
+
+	ptrArgs := make(map[token.Pos]pointerArgs) // all pointer arguments, whether or not they are safe
+	var uses []resultUse                       // calls whose result is stored or returned
+	// useResults records any calls found in exprs, whose results are used in the way described by kind.
+	useResults := func(fdec *ast.FuncDecl, exprs []ast.Expr, kind EscapeKind) {
+		for _, expr := range exprs {
+			if call, ok := expr.(*ast.CallExpr); ok {
+				uses = append(uses, resultUse{caller: fdec, call: call, kind: kind})
+			}
+		}
+	}
 
 	nodeFilter := []ast.Node{
 		(*ast.FuncDecl)(nil),
 		(*ast.AssignStmt)(nil),
 		(*ast.CompositeLit)(nil),
+		(*ast.SendStmt)(nil),
+		(*ast.ReturnStmt)(nil),
 		(*ast.CallExpr)(nil),
 	}
 
 	visitedDeclarations := make(map[token.Pos]struct{})
 
+	// markWrite marks any pointer arguments found in exprs unsafe, and records how they escaped.
+	markWrite := func(fdec *ast.FuncDecl, exprs []ast.Expr, kind EscapeKind) {
+		if !safePtrArgs.MarkUnsafe(fdec.Pos(), exprs) {
+			return
+		}
+		if _, ok := visitedDeclarations[fdec.Pos()]; !ok {
+			stats.AddCount(stats.StatPtrFuncWritesPtr, 1)
+		}
+		sig := graph.DeclSignature(fdec)
+		writePtrSigs[sig] = struct{}{}
+		if _, ok := escapes[sig]; !ok {
+			escapes[sig] = make(map[EscapeKind]struct{})
+		}
+		escapes[sig][kind] = struct{}{}
+	}
+
 	inspect.WithStack(nodeFilter, func(n ast.Node, push bool, stack []ast.Node) bool {
 		if !push {
 			return true
 		}
-		switch typed := n.(type) {
-		case *ast.FuncDecl:
+		if _, ok := n.(*ast.FuncDecl); ok {
 			// all pointer args are safe until proven otherwise.
-			safePtrArgs[n.Pos()] = parsePointerArgs(typed)
-
-		// TODO: ask if this is more than a 'little' duplication
+			safePtrArgs[n.Pos()] = parsePointerArgs(n.(*ast.FuncDecl))
+			ptrArgs[n.Pos()] = parsePointerArgs(n.(*ast.FuncDecl))
+			return true
+		}
+		fdec := outermostFuncDeclPos(stack)
+		if fdec == nil {
+			return true
+		}
+		if _, ok := safePtrArgs[fdec.Pos()]; !ok {
+			log.Printf("sanity check failed: %T found before outer declaration of %v", n, graph.DeclSignature(fdec))
+			return true
+		}
+		switch typed := n.(type) {
 		case *ast.AssignStmt:
 			// a pointer argument used on the RHS of an assign statement is marked unsafe.
-			fdec := outermostFuncDeclPos(stack)
-			if fdec == nil {
+			if len(typed.Lhs) != len(typed.Rhs) {
+				kind := assignEscapeKind(typed.Lhs[0], fdec, stack)
+				markWrite(fdec, typed.Rhs, kind)
+				useResults(fdec, typed.Rhs, kind)
 				return true
 			}
-			if _, ok := safePtrArgs[fdec.Pos()]; ok {
-				if safePtrArgs.MarkUnsafe(fdec.Pos(), typed.Rhs) {
-					// we found a pointer argument on the RHS of an assignment; mark the outer function.
-					if _, ok := visitedDeclarations[fdec.Pos()]; !ok {
-						stats.AddCount(stats.StatPtrFuncWritesPtr, 1)
-					}
-					writePtrSigs[callgraph.SignatureFromFuncDecl(fdec)] = struct{}{}
-				}
-			} else {
-				log.Printf("sanity check failed: assign statement found before outer declaration of %v", callgraph.SignatureFromFuncDecl(fdec))
+			for i, rhs := range typed.Rhs {
+				kind := assignEscapeKind(typed.Lhs[i], fdec, stack)
+				markWrite(fdec, []ast.Expr{rhs}, kind)
+				useResults(fdec, []ast.Expr{rhs}, kind)
 			}
 
 		case *ast.CompositeLit:
 			// a pointer argument used inside a composite literal is marked unsafe.
-			fdec := outermostFuncDeclPos(stack)
-			if fdec == nil {
-				return true
+			markWrite(fdec, typed.Elts, EscapeCompositeLit)
+			useResults(fdec, typed.Elts, EscapeCompositeLit)
+
+		case *ast.SendStmt:
+			// a pointer argument sent on a channel is marked unsafe.
+			markWrite(fdec, []ast.Expr{typed.Value}, EscapeChanSend)
+			useResults(fdec, []ast.Expr{typed.Value}, EscapeChanSend)
+
+		case *ast.ReturnStmt:
+			// a pointer argument returned to the caller is only unsafe if the caller stores it.
+			if innermostFuncLit(stack) != nil {
+				return true // returns from a function literal don't return from the declaration
 			}
-			if _, ok := safePtrArgs[fdec.Pos()]; !ok {
-				log.Printf("sanity check failed: composite literal found before outer declaration of %v", callgraph.SignatureFromFuncDecl(fdec))
-			}
-			if safePtrArgs.MarkUnsafe(fdec.Pos(), typed.Elts) {
-				// we found a pointer argument stored in a composite literal; mark the outer function
-				if _, ok := visitedDeclarations[fdec.Pos()]; !ok {
-					stats.AddCount(stats.StatPtrFuncWritesPtr, 1)
+			for _, expr := range typed.Results {
+				if idx, ok := ptrArgIndex(ptrArgs[fdec.Pos()], expr); ok {
+					returns.addReturned(graph.DeclSignature(fdec), idx)
 				}
-				writePtrSigs[callgraph.SignatureFromFuncDecl(fdec)] = struct{}{}
 			}
+			useResults(fdec, typed.Results, EscapeReturn)
 
 		case *ast.CallExpr:
+			// a pointer argument appended to a slice is marked unsafe.
+			if id, ok := typed.Fun.(*ast.Ident); ok && id.Name == "append" && id.Obj == nil && len(typed.Args) > 1 {
+				markWrite(fdec, typed.Args[1:], EscapeAppend)
+				useResults(fdec, typed.Args[1:], EscapeAppend)
+				return true
+			}
 			// a pointer argument passed to a third-party function is marked unsafe.
-			if _, ok := knownPtrSignatures[callgraph.SignatureFromCallExpr(typed)]; ok {
+			if graph.Declares(graph.CallSignature(typed)) {
 				return true // if the signature is known; we found its declaration, so it can't be third-party
 			}
 			if acceptlist.IgnoreCall(packageResolver, typed, stack) {
 				return true // ignore third-party functions in the accept list
 			}
-			fdec := outermostFuncDeclPos(stack)
-			if fdec == nil {
-				return true
-			}
-			if _, ok := safePtrArgs[fdec.Pos()]; !ok {
-				log.Printf("sanity check failed: call expression found before outer declaration of %v", callgraph.SignatureFromFuncDecl(fdec))
-				return true
-			}
-			if safePtrArgs.MarkUnsafe(fdec.Pos(), typed.Args) {
+			if safePtrArgs.MarkUnsafe(fdec.Pos(), unsafeThirdPartyArgs(packageResolver, typed, stack)) {
 				// we found a pointer argument passed to this function call; mark the outer function as passing an
 				// argument to third-party code.
 				if _, ok := visitedDeclarations[fdec.Pos()]; !ok {
 					stats.AddCount(stats.StatPtrDeclCallsThirdPartyCode, 1)
 				}
-				thirdPartySigs[callgraph.SignatureFromFuncDecl(fdec)] = struct{}{}
+				thirdPartySigs[graph.DeclSignature(fdec)] = struct{}{}
 			}
 		}
 		return true
 	})
-	return safePtrArgs, writePtrSigs, thirdPartySigs
+
+	// carry returned pointer arguments through callers which return the result in turn, until no more are found.
+	for changed := true; changed; {
+		changed = false
+		for _, use := range uses {
+			if use.kind != EscapeReturn {
+				continue
+			}
+			for _, idx := range returns.returnedBy(use.call) {
+				if argIdx, ok := ptrArgIndex(ptrArgs[use.caller.Pos()], use.call.Args[idx]); ok {
+					changed = returns.addReturned(graph.DeclSignature(use.caller), argIdx) || changed
+				}
+			}
+		}
+	}
+	// a pointer argument passed to a function which returns it is unsafe wherever the result is stored.
+	for _, use := range uses {
+		for _, idx := range returns.returnedBy(use.call) {
+			if _, ok := ptrArgIndex(ptrArgs[use.caller.Pos()], use.call.Args[idx]); !ok {
+				continue
+			}
+			returns.addFlow(graph.DeclSignature(use.caller), graph.CallSignature(use.call))
+			if use.kind != EscapeReturn {
+				markWrite(use.caller, []ast.Expr{use.call.Args[idx]}, use.kind)
+			}
+		}
+	}
+	return safePtrArgs, writePtrSigs, thirdPartySigs, escapes, returns
+}
+
+// unsafeThirdPartyArgs returns the arguments of a third-party call which are not known to be safe from either the
+// accept list or the summary.
+func unsafeThirdPartyArgs(pr *packid.PackageResolver, call *ast.CallExpr, stack []ast.Node) []ast.Expr {
+	var result []ast.Expr
+	for idx, arg := range call.Args {
+		if !acceptlist.SafeArg(pr, call, stack, idx) && !summary.SafeArg(pr, call, stack, idx) {
+			result = append(result, arg)
+		}
+	}
+	return result
+}
+
+// resultUse describes a call found in a function declaration whose result is used as described by kind.
+type resultUse struct {
+	caller *ast.FuncDecl
+	call   *ast.CallExpr
+	kind   EscapeKind
+}
+
+// returnFlow tracks the pointer arguments which functions may return.
+type returnFlow struct {
+	graph *callgraph.Result
+	// returned maps signatures to the indices of the pointer arguments they may return.
+	returned map[callgraph.Signature][]int
+	// flows maps each signature to the signatures of the calls whose results it stores or returns, when the
+	// result may be one of its own pointer arguments.
+	flows map[callgraph.Signature]map[callgraph.Signature]struct{}
+}
+
+func newReturnFlow(graph *callgraph.Result) *returnFlow {
+	return &returnFlow{
+		graph:    graph,
+		returned: make(map[callgraph.Signature][]int),
+		flows:    make(map[callgraph.Signature]map[callgraph.Signature]struct{}),
+	}
+}
+
+// addReturned records that the function with the provided signature may return the pointer argument at idx. It
+// returns true only if this was not already known.
+func (rf *returnFlow) addReturned(sig callgraph.Signature, idx int) bool {
+	if contains(rf.returned[sig], idx) {
+		return false
+	}
+	rf.returned[sig] = append(rf.returned[sig], idx)
+	return true
+}
+
+func (rf *returnFlow) addFlow(caller, callee callgraph.Signature) {
+	if _, ok := rf.flows[caller]; !ok {
+		rf.flows[caller] = make(map[callgraph.Signature]struct{})
+	}
+	rf.flows[caller][callee] = struct{}{}
+}
+
+// returnedBy returns the indices of the arguments of the provided call which may be returned by it. Calls which
+// could only be resolved by name and arity may return any argument returned by one of the declarations they refer
+// to.
+func (rf *returnFlow) returnedBy(call *ast.CallExpr) []int {
+	sig := rf.graph.CallSignature(call)
+	var result []int
+	if aliases, ok := rf.graph.Aliases[sig]; ok {
+		result = rf.returnedByAny(aliases)
+	} else {
+		result = rf.returned[sig]
+	}
+	var inBounds []int
+	for _, idx := range result {
+		if idx < len(call.Args) {
+			inBounds = append(inBounds, idx)
+		}
+	}
+	return inBounds
+}
+
+// returnedByAny returns the union of the indices of pointer arguments returned by any of the provided signatures.
+func (rf *returnFlow) returnedByAny(sigs []callgraph.Signature) []int {
+	var result []int
+	for _, sig := range sigs {
+		for _, idx := range rf.returned[sig] {
+			if !contains(result, idx) {
+				result = append(result, idx)
+			}
+		}
+	}
+	return result
+}
+
+// ptrArgIndex returns the positional index of the pointer argument named by the provided expression, if any.
+func ptrArgIndex(args pointerArgs, expr ast.Expr) (int, bool) {
+	id, ok := expr.(*ast.Ident)
+	if !ok || id.Obj == nil {
+		return 0, false
+	}
+	idx, ok := args[id.Obj.Pos()]
+	return idx, ok
+}
+
+// assignEscapeKind describes how a pointer escapes when it is assigned to the provided left-hand side expression.
+func assignEscapeKind(lhs ast.Expr, fdec *ast.FuncDecl, stack []ast.Node) EscapeKind {
+	switch typed := lhs.(type) {
+	case *ast.IndexExpr:
+		return EscapeIndex
+	case *ast.Ident:
+		// local variables declared outside of an enclosing function literal are captured by the closure.
+		lit := innermostFuncLit(stack)
+		if lit == nil || typed.Obj == nil {
+			break
+		}
+		declPos := typed.Obj.Pos()
+		if declPos > fdec.Pos() && declPos < fdec.End() && (declPos < lit.Pos() || declPos >= lit.End()) {
+			return EscapeClosureVar
+		}
+	}
+	return EscapeAssign
+}
+
+// innermostFuncLit returns the innermost function literal in the provided stack, if any.
+func innermostFuncLit(stack []ast.Node) *ast.FuncLit {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if lit, ok := stack[i].(*ast.FuncLit); ok {
+			return lit
+		}
+	}
+	return nil
 }
 
 // outermostFuncDeclPos returns the source position of the outermost function declaration in  the
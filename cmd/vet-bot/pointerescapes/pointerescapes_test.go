@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/github-vet/bots/cmd/vet-bot/pointerescapes"
+	"github.com/stretchr/testify/assert"
 	"golang.org/x/tools/go/analysis/analysistest"
 )
 
@@ -13,3 +14,24 @@ func Test(t *testing.T) {
 	result := analysistest.Run(t, testdata, pointerescapes.Analyzer, "devtest")
 	fmt.Println(result[0].Result)
 }
+
+func TestReturnFlow(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, pointerescapes.Analyzer, "returns")
+	result := results[0].Result.(*pointerescapes.Result)
+	returned := make(map[string][]int)
+	safe := make(map[string][]int)
+	for sig, idxs := range result.ReturnedPtrs {
+		returned[sig.Name] = idxs
+	}
+	for sig, idxs := range result.SafePtrs {
+		safe[sig.Name] = idxs
+	}
+	assert.Equal(t, []int{0}, returned["id"])
+	assert.Equal(t, []int{1}, returned["second"])
+	assert.Equal(t, []int{0}, returned["chained"])
+	assert.Empty(t, returned["stores"])
+	assert.Equal(t, []int{0}, safe["id"])
+	assert.Equal(t, []int{0}, safe["discards"])
+	assert.Empty(t, safe["stores"])
+}
@@ -0,0 +1,23 @@
+package returns
+
+var global *int
+
+func id(x *int) *int {
+	return x
+}
+
+func second(x, y *int) *int {
+	return y
+}
+
+func chained(x *int) *int {
+	return id(x)
+}
+
+func stores(x *int) {
+	global = chained(x)
+}
+
+func discards(x *int) {
+	println(id(x))
+}
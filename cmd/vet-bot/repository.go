@@ -1,31 +1,24 @@
 package main
 
 import (
-	"archive/tar"
-	"bufio"
 	"bytes"
-	"compress/gzip"
 	"fmt"
 	"go/ast"
-	"go/format"
 	"go/parser"
 	"go/token"
-	"io"
-	"io/ioutil"
+	"go/types"
 	"log"
-	"net/http"
 	"strings"
 
-	"github.com/github-vet/bots/cmd/vet-bot/callgraph"
+	"github.com/github-vet/bots/cmd/vet-bot/classify"
+	"github.com/github-vet/bots/cmd/vet-bot/driver"
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
 	"github.com/github-vet/bots/cmd/vet-bot/loopclosure"
 	"github.com/github-vet/bots/cmd/vet-bot/looppointer"
-	"github.com/github-vet/bots/cmd/vet-bot/nogofunc"
-	"github.com/github-vet/bots/cmd/vet-bot/packid"
-	"github.com/github-vet/bots/cmd/vet-bot/pointerescapes"
 	"github.com/github-vet/bots/cmd/vet-bot/stats"
-	"github.com/google/go-github/v32/github"
+	"github.com/github-vet/bots/cmd/vet-bot/typecheck"
+	"github.com/github-vet/bots/internal/snippet"
 	"golang.org/x/tools/go/analysis"
-	"golang.org/x/tools/go/analysis/passes/inspect"
 )
 
 // Repository encapsulates the information needed to lookup a GitHub repository.
@@ -44,6 +37,19 @@ type VetResult struct {
 	End          token.Position
 	Message      string
 	ExtraInfo    string
+	Analyzer     string
+	SubFindings  []SubFinding
+	SuggestedFix string // a unified diff describing the fix suggested by the analyzer, if any
+	Class        classify.Class
+	Fingerprint  []byte // identifies near-duplicates of the quote; nil if the quote is too short to fingerprint
+	DuplicateOf  int64  // the ID of the finding which this result nearly duplicates; 0 if it is the original
+}
+
+// SubFinding describes one of several issues found in the same snippet of code, which were merged into a single
+// VetResult.
+type SubFinding struct {
+	Start   token.Position
+	Message string
 }
 
 // Permalink returns the GitHub permalink which refers to the snippet of code retrieved by the VetResult.
@@ -62,89 +68,89 @@ func VetRepositoryBulk(bot *VetBot, ir *IssueReporter, repo Repository) error {
 		log.Printf("failed to retrieve root commit ID for repo %s/%s", repo.Owner, repo.Repo)
 		return err
 	}
-	url, _, err := bot.client.GetArchiveLink(repo.Owner, repo.Repo, github.Tarball, nil, false)
-	if err != nil {
-		log.Printf("failed to get tar link for %s/%s: %v", repo.Owner, repo.Repo, err)
-		return err
-	}
+	return VetSource(bot, ir, NewGithubTarballSource(bot, repo), repo, rootCommitID)
+}
+
+// VetSource reads and parses each go file found in the provided source, analyzes them, and reports the results
+// as if they were found in the provided repository at the provided commit.
+func VetSource(bot *VetBot, ir *IssueReporter, src Source, repo Repository, rootCommitID string) error {
 	fset := token.NewFileSet()
 	contents := make(map[string][]byte)
+	classes := make(map[string]classify.Class)
 	var files []*ast.File
-	if err := func() error {
-		resp, err := http.Get(url.String())
-		if err != nil {
-			log.Printf("failed to download tar contents: %v", err)
-			return err
+	goMods := make(map[string][]byte)
+	err := src.ReadFiles(func(path string) bool {
+		return isGoMod(path) || !IgnoreFile(path)
+	}, func(path string, bytes []byte) {
+		if isGoMod(path) {
+			goMods[path] = bytes
+			return
 		}
-		defer resp.Body.Close()
-		unzipped, err := gzip.NewReader(resp.Body)
+		file, err := parser.ParseFile(fset, path, bytes, parser.AllErrors)
 		if err != nil {
-			log.Printf("unable to initialize unzip stream: %v", err)
-			return err
-		}
-		reader := tar.NewReader(unzipped)
-		log.Printf("reading contents of %s/%s", repo.Owner, repo.Repo)
-		for {
-			header, err := reader.Next()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				log.Printf("failed to read tar entry")
-				return err
-			}
-			name := header.Name
-			split := strings.SplitN(name, "/", 2)
-			if len(split) < 2 {
-				continue // we only care about files in a subdirectory (due to how GitHub returns archives).
-			}
-			realName := split[1]
-			switch header.Typeflag {
-			case tar.TypeReg:
-				if IgnoreFile(realName) {
-					continue
-				}
-				bytes, err := ioutil.ReadAll(reader)
-				if err != nil {
-					log.Printf("error reading contents of %s: %v", realName, err)
-				}
-				file, err := parser.ParseFile(fset, realName, bytes, parser.AllErrors)
-				if err != nil {
-					log.Printf("failed to parse file %s: %v", realName, err)
-					continue
-				}
-				countLines(realName, bytes)
-				files = append(files, file)
-				contents[fset.File(file.Pos()).Name()] = bytes
-			}
+			log.Printf("failed to parse file %s: %v", path, err)
+			return
 		}
-		return nil
-	}(); err != nil {
+		class := bot.classifier.Classify(path, bytes)
+		countLines(bytes, class)
+		files = append(files, file)
+		contents[fset.File(file.Pos()).Name()] = bytes
+		classes[fset.File(file.Pos()).Name()] = class
+	})
+	if err != nil {
 		return err
 	}
-	VetRepo(contents, files, fset, ReportFinding(ir, fset, rootCommitID, repo))
-	countFileStats(files)
+	var typeCheck typecheck.Result
+	if bot.opts.TypeCheck {
+		typeCheck = typecheck.Check(fset, files, contents, goMods)
+	}
+	onFind := ReportFinding(ir, fset, rootCommitID, repo, classes)
+	root, fixing := fixRoot(bot, src)
+	fixer := NewFixer(fset)
+	if fixing {
+		onFind = fixer.Collect(onFind)
+	}
+	VetRepo(contents, goMods, files, fset, typeCheck.Info, onFind)
+	if fixing {
+		fixer.Apply(root, contents)
+	}
+	countFileStats(fset, files)
+	stats.AddCount(stats.StatPackagesTypeChecked, typeCheck.Checked)
+	stats.AddCount(stats.StatPackagesTypeCheckFailed, typeCheck.Failed)
 	stats.FlushStats(bot.statsWriter, repo.Owner, repo.Repo)
-	return nil
+	return ir.FinishRepository(repo)
 }
 
-func countLines(filename string, contents []byte) {
+func countLines(contents []byte, class classify.Class) {
 	lines := bytes.Count(contents, []byte{'\n'})
 	stats.AddCount(stats.StatSloc, lines)
-	if strings.HasSuffix(filename, "_test.go") {
+	switch class {
+	case classify.ClassTest:
+		stats.AddCount(stats.StatTestFile, 1)
 		stats.AddCount(stats.StatSlocTest, lines)
-	}
-	if strings.HasPrefix(filename, "vendor") {
+	case classify.ClassGenerated:
+		stats.AddCount(stats.StatGeneratedFile, 1)
+		stats.AddCount(stats.StatSlocGenerated, lines)
+	case classify.ClassExample:
+		stats.AddCount(stats.StatExampleFile, 1)
+		stats.AddCount(stats.StatSlocExample, lines)
+	case classify.ClassVendored:
+		stats.AddCount(stats.StatVendoredFile, 1)
 		stats.AddCount(stats.StatSlocVendored, lines)
 	}
 }
 
-func countFileStats(files []*ast.File) {
+func countFileStats(fset *token.FileSet, files []*ast.File) {
 	for _, file := range files {
-		stats.AddFile(file.Name.Name)
+		stats.AddFile(fset.File(file.Pos()).Name())
 	}
 }
 
+// isGoMod returns true if the file is a go.mod file.
+func isGoMod(filename string) bool {
+	return filename == "go.mod" || strings.HasSuffix(filename, "/go.mod")
+}
+
 // IgnoreFile returns true if the file should be ignored.
 func IgnoreFile(filename string) bool {
 	if strings.HasSuffix(filename, ".pb.go") {
@@ -157,53 +163,46 @@ func IgnoreFile(filename string) bool {
 // also has access to the contents and name of the file being observed.
 type Reporter func(map[string][]byte) func(analysis.Diagnostic) // yay for currying!
 
-// VetRepo runs all static analyzers on the parsed set of files provided. When an issue is found,
-// the Reporter provided in onFind is triggered.
-func VetRepo(contents map[string][]byte, files []*ast.File, fset *token.FileSet, onFind Reporter) {
-	stats.Clear()
-	pass := analysis.Pass{
-		Fset:     fset,
-		Files:    files,
-		Report:   onFind(contents),
-		ResultOf: make(map[*analysis.Analyzer]interface{}),
-	}
-	var err error
-	pass.ResultOf[inspect.Analyzer], err = inspect.Analyzer.Run(&pass)
-	if err != nil {
-		log.Printf("failed inspection analysis: %v", err)
-		return
-	}
+// vetAnalyzers lists the analyzers whose findings are reported by VetBot. Any analyzers they require are run
+// automatically.
+var vetAnalyzers = []*analysis.Analyzer{
+	loopclosure.Analyzer,
+	looppointer.Analyzer,
+}
 
-	pass.ResultOf[packid.Analyzer], err = packid.Analyzer.Run(&pass)
-	if err != nil {
-		log.Printf("failed packid analysis: %v", err)
-	}
+// ruleIDs maps the names of analyzers which were renamed after their findings were first published to the names
+// under which their findings are still reported. The multichecker used by rangevet requires analyzer names to be
+// valid identifiers.
+var ruleIDs = map[string]string{
+	"loopclosure_augmented": "loopclosure-augmented",
+}
 
-	pass.ResultOf[callgraph.Analyzer], err = callgraph.Analyzer.Run(&pass)
-	if err != nil {
-		log.Printf("failed callgraph analysis: %v", err)
-		return
+// ruleID returns the name under which findings of the named analyzer are reported by every sink.
+func ruleID(analyzer string) string {
+	if id, ok := ruleIDs[analyzer]; ok {
+		return id
 	}
+	return analyzer
+}
 
-	pass.ResultOf[nogofunc.Analyzer], err = nogofunc.Analyzer.Run(&pass)
-	if err != nil {
-		log.Printf("failed nogofunc analysis: %v", err)
-		return
+// VetRepo runs all static analyzers on the parsed set of files provided. When an issue is found,
+// the Reporter provided in onFind is triggered. goMods maps the slash-separated path of each go.mod file found in the
+// repository to its contents. If info is non-nil, analyzers use it in place of their heuristics wherever it contains
+// type information.
+func VetRepo(contents map[string][]byte, goMods map[string][]byte, files []*ast.File, fset *token.FileSet, info *types.Info, onFind Reporter) {
+	stats.Clear()
+	inputs := map[*analysis.Analyzer]interface{}{
+		gomod.Analyzer: gomod.Analyze(fset, files, gomod.Files(goMods)),
 	}
-
-	pass.ResultOf[pointerescapes.Analyzer], err = pointerescapes.Analyzer.Run(&pass)
+	outcomes, err := driver.RunWithInputs(fset, files, info, inputs, vetAnalyzers, onFind(contents))
 	if err != nil {
-		log.Printf("failed pointerescapes analysis: %v", err)
+		log.Printf("invalid analyzers: %v", err)
 		return
 	}
-
-	_, err = loopclosure.Analyzer.Run(&pass)
-	if err != nil {
-		log.Printf("failed loopclosure analysis: %v", err)
-	}
-	_, err = looppointer.Analyzer.Run(&pass)
-	if err != nil {
-		log.Printf("failed looppointer analysis: %v", err)
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			log.Printf("failed %s analysis: %v", outcome.Analyzer.Name, outcome.Err)
+		}
 	}
 }
 
@@ -226,8 +225,8 @@ func GetRootCommitID(bot *VetBot, repo Repository) (string, error) {
 }
 
 // ReportFinding curries several parameters into a function whose signature matches that expected
-// by the analysis package for a Diagnostic function.
-func ReportFinding(ir *IssueReporter, fset *token.FileSet, rootCommitID string, repo Repository) Reporter {
+// by the analysis package for a Diagnostic function. classes holds the class of each file, keyed by its name.
+func ReportFinding(ir *IssueReporter, fset *token.FileSet, rootCommitID string, repo Repository, classes map[string]classify.Class) Reporter {
 	return func(contents map[string][]byte) func(analysis.Diagnostic) {
 		return func(d analysis.Diagnostic) {
 			if len(d.Related) < 1 {
@@ -239,43 +238,33 @@ func ReportFinding(ir *IssueReporter, fset *token.FileSet, rootCommitID string,
 			if len(d.Related) >= 2 {
 				extraInfo = d.Related[1].Message
 			}
+			var subFindings []SubFinding
+			if len(d.Related) > 2 {
+				for _, related := range d.Related[2:] {
+					subFindings = append(subFindings, SubFinding{
+						Start:   fset.Position(related.Pos),
+						Message: related.Message,
+					})
+				}
+			}
 			start := fset.Position(d.Pos)
 			end := fset.Position(d.End)
+			suggestedFix := suggestedFixDiff(fset, d, contents)
 			// split off into a separate thread so any API call to create the issue doesn't block the remaining analysis.
 			ir.ReportVetResult(VetResult{
 				Repository:   repo,
 				FilePath:     fset.File(d.Pos).Name(),
 				RootCommitID: rootCommitID,
-				Quote:        QuoteFinding(contents[filename], start.Line, end.Line),
+				Quote:        snippet.Quote(contents[filename], start.Line, end.Line),
 				Start:        start,
 				End:          end,
 				Message:      d.Message,
 				ExtraInfo:    extraInfo,
+				Analyzer:     ruleID(d.Category),
+				SubFindings:  subFindings,
+				SuggestedFix: suggestedFix,
+				Class:        classes[filename],
 			})
 		}
 	}
 }
-
-// QuoteFinding retrieves the snippet of code that caused the VetResult.
-func QuoteFinding(contents []byte, lineStart, lineEnd int) string {
-	sc := bufio.NewScanner(bytes.NewReader(contents))
-	line := 0
-	var sb strings.Builder
-	for sc.Scan() && line < lineEnd {
-		line++
-		if lineStart == line { // truncate whitespace from the first line (fixes formatting later)
-			sb.WriteString(strings.TrimSpace(sc.Text()) + "\n")
-		}
-		if lineStart < line && line <= lineEnd {
-			sb.WriteString(sc.Text() + "\n")
-		}
-	}
-
-	// run go fmt on the snippet to remove leading whitespace
-	snippet := sb.String()
-	formatted, err := format.Source([]byte(snippet))
-	if err != nil {
-		return snippet
-	}
-	return string(formatted)
-}
@@ -0,0 +1,144 @@
+// Package sarif contains the subset of the SARIF 2.1.0 object model needed to report findings from vet-bot. See
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html for the full specification.
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Version is the version of the SARIF specification implemented by this package.
+const Version = "2.1.0"
+
+// Schema is the URI of the JSON schema for the version of SARIF implemented by this package.
+const Schema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// Log is the top-level object of a SARIF file.
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []*Run `json:"runs"`
+}
+
+// NewLog creates an empty log.
+func NewLog() *Log {
+	return &Log{
+		Version: Version,
+		Schema:  Schema,
+		Runs:    []*Run{},
+	}
+}
+
+// Write writes the log to the provided writer as indented JSON.
+func (l *Log) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(l)
+}
+
+// Run describes a single invocation of an analysis tool.
+type Run struct {
+	Tool                     Tool                    `json:"tool"`
+	VersionControlProvenance []VersionControlDetails `json:"versionControlProvenance,omitempty"`
+	Results                  []Result                `json:"results"`
+}
+
+// NewRun creates a run for the tool with the provided name.
+func NewRun(toolName, informationURI string) *Run {
+	return &Run{
+		Tool: Tool{
+			Driver: Driver{
+				Name:           toolName,
+				InformationURI: informationURI,
+			},
+		},
+		Results: []Result{},
+	}
+}
+
+// Tool describes the analysis tool which produced a run.
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+// Driver describes the component of the tool which performs the analysis.
+type Driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules,omitempty"`
+}
+
+// Rule describes a kind of result which can be reported by the tool. The SARIF specification calls this
+// object a reportingDescriptor.
+type Rule struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name,omitempty"`
+	ShortDescription *Message `json:"shortDescription,omitempty"`
+	FullDescription  *Message `json:"fullDescription,omitempty"`
+	HelpURI          string   `json:"helpUri,omitempty"`
+}
+
+// VersionControlDetails describes the revision of the repository which was analyzed.
+type VersionControlDetails struct {
+	RepositoryURI string `json:"repositoryUri"`
+	RevisionID    string `json:"revisionId,omitempty"`
+}
+
+// Result describes a single finding.
+type Result struct {
+	RuleID              string            `json:"ruleId,omitempty"`
+	Level               string            `json:"level,omitempty"`
+	Message             Message           `json:"message"`
+	Locations           []Location        `json:"locations,omitempty"`
+	RelatedLocations    []Location        `json:"relatedLocations,omitempty"`
+	CodeFlows           []CodeFlow        `json:"codeFlows,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+}
+
+// Message is a human-readable message.
+type Message struct {
+	Text string `json:"text"`
+}
+
+// Location describes where a result was found. A location may consist only of a message, in case it refers to
+// something which cannot be found in a file.
+type Location struct {
+	PhysicalLocation *PhysicalLocation `json:"physicalLocation,omitempty"`
+	Message          *Message          `json:"message,omitempty"`
+}
+
+// CodeFlow describes one or more paths through the code which lead to a result.
+type CodeFlow struct {
+	Message     *Message     `json:"message,omitempty"`
+	ThreadFlows []ThreadFlow `json:"threadFlows"`
+}
+
+// ThreadFlow is a sequence of locations visited along a single path.
+type ThreadFlow struct {
+	Locations []ThreadFlowLocation `json:"locations"`
+}
+
+// ThreadFlowLocation is a single step in a ThreadFlow.
+type ThreadFlowLocation struct {
+	Location Location `json:"location"`
+}
+
+// PhysicalLocation refers to a region within a file.
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+// ArtifactLocation refers to a file.
+type ArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// Region describes a contiguous portion of a file. Lines and columns are 1-based.
+type Region struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
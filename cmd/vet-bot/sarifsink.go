@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/github-vet/bots/cmd/vet-bot/sarif"
+	"golang.org/x/tools/go/analysis"
+)
+
+// sarifRules lists a SARIF rule for each analyzer which reports findings.
+var sarifRules = rulesFromAnalyzers(vetAnalyzers)
+
+func rulesFromAnalyzers(analyzers []*analysis.Analyzer) []sarif.Rule {
+	result := make([]sarif.Rule, 0, len(analyzers))
+	for _, a := range analyzers {
+		result = append(result, ruleFromAnalyzer(a))
+	}
+	return result
+}
+
+func ruleFromAnalyzer(a *analysis.Analyzer) sarif.Rule {
+	short := strings.TrimSpace(a.Doc)
+	if idx := strings.Index(short, "\n"); idx != -1 {
+		short = short[:idx]
+	}
+	return sarif.Rule{
+		ID:               ruleID(a.Name),
+		Name:             ruleID(a.Name),
+		ShortDescription: &sarif.Message{Text: short},
+		FullDescription:  &sarif.Message{Text: strings.TrimSpace(a.Doc)},
+		HelpURI:          "https://github.com/github-vet/bots/tree/main/cmd/vet-bot",
+	}
+}
+
+// SarifSink collects findings into SARIF logs, with one run per repository. Either every run is written to a
+// single log when the sink is closed, or each run is written to its own log as soon as its repository is
+// finished. It does not record findings in the database, so near-duplicates of the findings it collects are not
+// linked to them; they share the same partial fingerprint instead.
+type SarifSink struct {
+	w      io.WriteCloser                                // destination of the aggregated log; nil if writing per repository
+	create func(repo Repository) (io.WriteCloser, error) // opens the destination of each per-repository log
+	runs   []*sarif.Run
+	byRepo map[Repository]*sarif.Run
+}
+
+// NewSarifSink creates a sink which writes findings from every repository to the provided writer when it is closed.
+func NewSarifSink(w io.WriteCloser) *SarifSink {
+	return &SarifSink{
+		w:      w,
+		byRepo: make(map[Repository]*sarif.Run),
+	}
+}
+
+// NewPerRepositorySarifSink creates a sink which writes the findings for each repository to the writer returned by
+// create once the repository is finished.
+func NewPerRepositorySarifSink(create func(repo Repository) (io.WriteCloser, error)) *SarifSink {
+	return &SarifSink{
+		create: create,
+		byRepo: make(map[Repository]*sarif.Run),
+	}
+}
+
+// Consume adds the VetResult to the run for its repository.
+func (ss *SarifSink) Consume(result VetResult, md5Sum Md5Checksum) (int64, error) {
+	run := ss.runFor(result.Repository, result.RootCommitID)
+	run.Results = append(run.Results, sarifResult(result, md5Sum))
+	return 0, nil
+}
+
+// FinishRepository writes the run for the provided repository to its own log, if the sink writes one log per
+// repository.
+func (ss *SarifSink) FinishRepository(repo Repository) error {
+	if ss.create == nil {
+		return nil
+	}
+	run := ss.runFor(repo, "")
+	delete(ss.byRepo, repo)
+	for i, r := range ss.runs {
+		if r == run {
+			ss.runs = append(ss.runs[:i], ss.runs[i+1:]...)
+			break
+		}
+	}
+	w, err := ss.create(repo)
+	if err != nil {
+		return fmt.Errorf("cannot create SARIF file for %s/%s: %w", repo.Owner, repo.Repo, err)
+	}
+	return writeSarifLog(w, []*sarif.Run{run})
+}
+
+// Close writes the aggregated log, if the sink is not writing one log per repository.
+func (ss *SarifSink) Close() error {
+	if ss.w == nil {
+		return nil
+	}
+	return writeSarifLog(ss.w, ss.runs)
+}
+
+func (ss *SarifSink) runFor(repo Repository, rootCommitID string) *sarif.Run {
+	if run, ok := ss.byRepo[repo]; ok {
+		return run
+	}
+	run := sarif.NewRun("vet-bot", "https://github.com/github-vet/bots")
+	run.Tool.Driver.Rules = sarifRules
+	if rootCommitID != "" {
+		run.VersionControlProvenance = []sarif.VersionControlDetails{{
+			RepositoryURI: fmt.Sprintf("https://github.com/%s/%s", repo.Owner, repo.Repo),
+			RevisionID:    rootCommitID,
+		}}
+	}
+	ss.byRepo[repo] = run
+	ss.runs = append(ss.runs, run)
+	return run
+}
+
+// writeSarifLog writes a log containing the provided runs and closes the writer.
+func writeSarifLog(w io.WriteCloser, runs []*sarif.Run) error {
+	sarifLog := sarif.NewLog()
+	sarifLog.Runs = append(sarifLog.Runs, runs...)
+	if err := sarifLog.Write(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// sarifResult converts a VetResult into a SARIF result.
+func sarifResult(result VetResult, md5Sum Md5Checksum) sarif.Result {
+	fingerprints := map[string]string{
+		"quoteMd5/v1": fmt.Sprintf("%x", md5Sum),
+	}
+	if result.Fingerprint != nil {
+		fingerprints["quoteTokensMd5/v1"] = fmt.Sprintf("%x", result.Fingerprint)
+	}
+	return sarif.Result{
+		RuleID:  result.Analyzer,
+		Level:   "warning",
+		Message: sarif.Message{Text: result.Message},
+		Locations: []sarif.Location{{
+			PhysicalLocation: &sarif.PhysicalLocation{
+				ArtifactLocation: sarif.ArtifactLocation{URI: result.FilePath, URIBaseID: "%SRCROOT%"},
+				Region: &sarif.Region{
+					StartLine:   result.Start.Line,
+					StartColumn: result.Start.Column,
+					EndLine:     result.End.Line,
+					EndColumn:   result.End.Column,
+				},
+			},
+		}},
+		RelatedLocations:    sarifSubFindings(result),
+		CodeFlows:           callgraphCodeFlows(result.ExtraInfo),
+		PartialFingerprints: fingerprints,
+	}
+}
+
+// sarifSubFindings converts the SubFindings of a VetResult into related locations.
+func sarifSubFindings(result VetResult) []sarif.Location {
+	var locations []sarif.Location
+	for _, sub := range result.SubFindings {
+		locations = append(locations, sarif.Location{
+			PhysicalLocation: &sarif.PhysicalLocation{
+				ArtifactLocation: sarif.ArtifactLocation{URI: result.FilePath, URIBaseID: "%SRCROOT%"},
+				Region: &sarif.Region{
+					StartLine:   sub.Start.Line,
+					StartColumn: sub.Start.Column,
+				},
+			},
+			Message: &sarif.Message{Text: sub.Message},
+		})
+	}
+	return locations
+}
+
+// maxThreadFlows bounds the number of paths reported for each callgraph, since the number of paths through a
+// graph can be exponential in its size.
+const maxThreadFlows = 20
+
+var (
+	dotEdgeRegexp   = regexp.MustCompile(`^\s*"(.*)" -> \{(.*)\}\s*$`)
+	dotTargetRegexp = regexp.MustCompile(`"([^"]*)";`)
+)
+
+// callgraphCodeFlows converts each graphviz dot graph found in the ExtraInfo of a VetResult into a SARIF code flow.
+// Each path through the graph from a root to a leaf becomes a separate thread flow. The sentence preceding each graph
+// is used as the message of the code flow.
+func callgraphCodeFlows(extraInfo string) []sarif.CodeFlow {
+	var result []sarif.CodeFlow
+	var description string
+	var graph map[string][]string
+	sc := bufio.NewScanner(strings.NewReader(extraInfo))
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case graph == nil && strings.HasPrefix(line, "digraph"):
+			graph = make(map[string][]string)
+		case graph == nil:
+			description = line
+		case line == "}":
+			if flow, ok := codeFlowFromGraph(graph, description); ok {
+				result = append(result, flow)
+			}
+			graph = nil
+		default:
+			match := dotEdgeRegexp.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			if _, ok := graph[match[1]]; !ok {
+				graph[match[1]] = nil
+			}
+			for _, to := range dotTargetRegexp.FindAllStringSubmatch(match[2], -1) {
+				graph[match[1]] = append(graph[match[1]], to[1])
+			}
+		}
+	}
+	return result
+}
+
+func codeFlowFromGraph(graph map[string][]string, description string) (sarif.CodeFlow, bool) {
+	hasIncoming := make(map[string]bool)
+	for from, neighbors := range graph {
+		sort.Strings(neighbors)
+		graph[from] = neighbors
+		for _, to := range neighbors {
+			hasIncoming[to] = true
+		}
+	}
+	var roots []string
+	for node := range graph {
+		if !hasIncoming[node] {
+			roots = append(roots, node)
+		}
+	}
+	sort.Strings(roots)
+
+	var flows []sarif.ThreadFlow
+	onPath := make(map[string]bool)
+	var path []string
+	var visit func(node string)
+	visit = func(node string) {
+		if len(flows) >= maxThreadFlows || onPath[node] {
+			return
+		}
+		onPath[node] = true
+		path = append(path, node)
+		if len(graph[node]) == 0 {
+			flows = append(flows, threadFlow(path))
+		}
+		for _, to := range graph[node] {
+			visit(to)
+		}
+		path = path[:len(path)-1]
+		onPath[node] = false
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+	if len(flows) == 0 {
+		return sarif.CodeFlow{}, false
+	}
+	return sarif.CodeFlow{
+		Message:     &sarif.Message{Text: strings.TrimSuffix(description, ":")},
+		ThreadFlows: flows,
+	}, true
+}
+
+func threadFlow(path []string) sarif.ThreadFlow {
+	locations := make([]sarif.ThreadFlowLocation, 0, len(path))
+	for _, node := range path {
+		locations = append(locations, sarif.ThreadFlowLocation{
+			Location: sarif.Location{Message: &sarif.Message{Text: node}},
+		})
+	}
+	return sarif.ThreadFlow{Locations: locations}
+}
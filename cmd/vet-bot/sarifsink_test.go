@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/github-vet/bots/cmd/vet-bot/sarif"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopWriteCloser struct {
+	bytes.Buffer
+}
+
+func (*nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestSarifSink(t *testing.T) {
+	var buf nopWriteCloser
+	sink := NewSarifSink(&buf)
+	_, err := sink.Consume(sinkTestResult, md5.Sum([]byte("quote")))
+	assert.NoError(t, err)
+	assert.NoError(t, sink.FinishRepository(sinkTestResult.Repository))
+	assert.NoError(t, sink.Close())
+
+	var decoded sarif.Log
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, sarif.Version, decoded.Version)
+	assert.Len(t, decoded.Runs, 1)
+	assert.Len(t, decoded.Runs[0].Tool.Driver.Rules, 2)
+	assert.Equal(t, "loopclosure-augmented", decoded.Runs[0].Tool.Driver.Rules[0].ID)
+	assert.Equal(t, "https://github.com/owner/repo", decoded.Runs[0].VersionControlProvenance[0].RepositoryURI)
+	assert.Equal(t, "rootcommitid", decoded.Runs[0].VersionControlProvenance[0].RevisionID)
+	assert.Len(t, decoded.Runs[0].Results, 1)
+	result := decoded.Runs[0].Results[0]
+	assert.Equal(t, "looppointer", result.RuleID)
+	assert.Equal(t, "message", result.Message.Text)
+	assert.Equal(t, "pkg/foo.go", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 3, result.Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Equal(t, 7, result.Locations[0].PhysicalLocation.Region.EndLine)
+}
+
+func TestPerRepositorySarifSink(t *testing.T) {
+	written := make(map[Repository]*nopWriteCloser)
+	sink := NewPerRepositorySarifSink(func(repo Repository) (io.WriteCloser, error) {
+		written[repo] = &nopWriteCloser{}
+		return written[repo], nil
+	})
+	other := Repository{Owner: "other", Repo: "repo"}
+	_, err := sink.Consume(sinkTestResult, md5.Sum([]byte("quote")))
+	assert.NoError(t, err)
+	assert.NoError(t, sink.FinishRepository(sinkTestResult.Repository))
+	assert.NoError(t, sink.FinishRepository(other))
+	assert.NoError(t, sink.Close())
+
+	assert.Len(t, written, 2)
+	var decoded sarif.Log
+	assert.NoError(t, json.Unmarshal(written[sinkTestResult.Repository].Bytes(), &decoded))
+	assert.Len(t, decoded.Runs, 1)
+	assert.Len(t, decoded.Runs[0].Results, 1)
+
+	assert.NoError(t, json.Unmarshal(written[other].Bytes(), &decoded))
+	assert.Len(t, decoded.Runs, 1)
+	assert.Len(t, decoded.Runs[0].Results, 0)
+}
+
+func TestCallgraphCodeFlows(t *testing.T) {
+	extraInfo := `The following graphviz dot graph describes paths through the callgraph that could lead to a function which writes a pointer argument:
+digraph G {
+  "(foo, 1)" -> {"(bar, 2)";"(baz, 1)";}
+  "(bar, 2)" -> {"(baz, 1)";}
+  "(baz, 1)" -> {}
+}
+
+No path was found through the callgraph that could lead to a function which passes a pointer to third-party code.
+`
+	flows := callgraphCodeFlows(extraInfo)
+	assert.Len(t, flows, 1)
+	assert.Equal(t, "The following graphviz dot graph describes paths through the callgraph that could lead to a function which writes a pointer argument", flows[0].Message.Text)
+	assert.Equal(t, [][]string{
+		{"(foo, 1)", "(bar, 2)", "(baz, 1)"},
+		{"(foo, 1)", "(baz, 1)"},
+	}, flowPaths(flows[0]))
+}
+
+func TestCallgraphCodeFlowsNone(t *testing.T) {
+	assert.Len(t, callgraphCodeFlows(""), 0)
+	assert.Len(t, callgraphCodeFlows("No path was found through the callgraph that could lead to a function calling a goroutine.\n"), 0)
+}
+
+func flowPaths(flow sarif.CodeFlow) [][]string {
+	var result [][]string
+	for _, tf := range flow.ThreadFlows {
+		var path []string
+		for _, loc := range tf.Locations {
+			path = append(path, loc.Location.Message.Text)
+		}
+		result = append(result, path)
+	}
+	return result
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/github-vet/bots/internal/db"
+	"github.com/google/go-github/v32/github"
+)
+
+// FindingSink receives each VetResult which is not a duplicate of a previously reported finding.
+type FindingSink interface {
+	// Consume records a single VetResult. md5Sum is the checksum of the quoted source code. Consume returns the ID
+	// of the finding recorded in the database, or 0 if the sink does not record findings in the database.
+	Consume(result VetResult, md5Sum Md5Checksum) (int64, error)
+	// FinishRepository is called once every finding in the provided repository has been consumed.
+	FinishRepository(repo Repository) error
+	// Close flushes any findings buffered by the sink and releases any resources it holds.
+	Close() error
+}
+
+// The names of each sink which can be selected via the -sink option.
+const (
+	SinkGithub   = "github"
+	SinkDatabase = "db"
+	SinkJSONL    = "jsonl"
+	SinkSarif    = "sarif"
+)
+
+// NewFindingSink constructs the FindingSink selected by the provided options.
+func NewFindingSink(bot *VetBot, opts opts) (FindingSink, error) {
+	switch opts.Sink {
+	case SinkGithub, "":
+		return &GithubIssueSink{bot: bot, owner: opts.TargetOwner, repo: opts.TargetRepo}, nil
+	case SinkDatabase:
+		return &DatabaseSink{bot: bot}, nil
+	case SinkJSONL:
+		return NewJSONLinesSink(os.Stdout), nil
+	case SinkSarif:
+		if opts.SarifPerRepo {
+			if err := os.MkdirAll(opts.SarifFile, 0755); err != nil {
+				return nil, fmt.Errorf("cannot create SARIF directory %s: %w", opts.SarifFile, err)
+			}
+			return NewPerRepositorySarifSink(func(repo Repository) (io.WriteCloser, error) {
+				return os.Create(filepath.Join(opts.SarifFile, repo.Owner+"_"+repo.Repo+".sarif"))
+			}), nil
+		}
+		file, err := os.Create(opts.SarifFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create SARIF file %s: %w", opts.SarifFile, err)
+		}
+		return NewSarifSink(file), nil
+	}
+	return nil, fmt.Errorf("unknown sink '%s'", opts.Sink)
+}
+
+// GithubIssueSink opens a GitHub issue for each finding and records both the finding and the issue in the
+// database.
+type GithubIssueSink struct {
+	bot   *VetBot
+	owner string
+	repo  string
+}
+
+// Consume opens a new GitHub issue to report the VetResult, unless the result is found in a file whose class
+// should not be reported to GitHub. Near-duplicates of a finding which already has an issue are reported by
+// commenting on that issue instead. The result is persisted to the database either way.
+func (gs *GithubIssueSink) Consume(result VetResult, md5Sum Md5Checksum) (int64, error) {
+	var iss *github.Issue
+	if original, ok := gs.originalIssue(result); ok && gs.bot.classifier.ShouldReport(result.Class) {
+		body := DuplicateComment(result)
+		_, _, err := gs.bot.client.CreateIssueComment(original.GithubOwner, original.GithubRepo, original.GithubID, &github.IssueComment{Body: &body})
+		if err != nil {
+			return 0, fmt.Errorf("error commenting on issue %d: %w", original.GithubID, err)
+		}
+		log.Printf("linked near-duplicate to issue %d", original.GithubID)
+	} else if gs.bot.classifier.ShouldReport(result.Class) {
+		issueRequest := CreateIssueRequest(result)
+		var err error
+		iss, _, err = gs.bot.client.CreateIssue(gs.owner, gs.repo, &issueRequest)
+		if err != nil {
+			return 0, fmt.Errorf("error opening new issue: %w", err)
+		}
+		log.Printf("opened new issue at %s", iss.GetHTMLURL())
+	}
+	return persistResult(gs.bot, result, iss, gs.owner, gs.repo, md5Sum)
+}
+
+// originalIssue returns the issue opened for the finding which the provided result nearly duplicates. ok is false if
+// the result is not a near-duplicate, or no issue was opened for the original finding.
+func (gs *GithubIssueSink) originalIssue(result VetResult) (issue db.Issue, ok bool) {
+	if result.DuplicateOf == 0 {
+		return db.Issue{}, false
+	}
+	issue, err := db.IssueDAO.FindByFinding(context.Background(), gs.bot.db, result.DuplicateOf)
+	if err != nil {
+		log.Printf("cannot find issue for finding %d: %v", result.DuplicateOf, err)
+		return db.Issue{}, false
+	}
+	return issue, issue.GithubID != 0
+}
+
+// FinishRepository is a no-op.
+func (gs *GithubIssueSink) FinishRepository(repo Repository) error {
+	return nil
+}
+
+// Close is a no-op.
+func (gs *GithubIssueSink) Close() error {
+	return nil
+}
+
+// DatabaseSink only records findings in the database.
+type DatabaseSink struct {
+	bot *VetBot
+}
+
+// Consume persists the VetResult to the database.
+func (ds *DatabaseSink) Consume(result VetResult, md5Sum Md5Checksum) (int64, error) {
+	return persistResult(ds.bot, result, nil, "", "", md5Sum)
+}
+
+// FinishRepository is a no-op.
+func (ds *DatabaseSink) FinishRepository(repo Repository) error {
+	return nil
+}
+
+// Close is a no-op.
+func (ds *DatabaseSink) Close() error {
+	return nil
+}
+
+// persistResult writes the provided VetResult and github.Issue to the database (if the issue is non-nil), and
+// returns the ID of the finding. It is not thread-safe (yet).
+func persistResult(bot *VetBot, result VetResult, issue *github.Issue, owner, repo string, md5Sum Md5Checksum) (int64, error) {
+	_, err := db.FindingDAO.Create(context.Background(), bot.db, db.Finding{
+		GithubOwner:  result.Owner,
+		GithubRepo:   result.Repo,
+		Filepath:     result.FilePath,
+		RootCommitID: result.RootCommitID,
+		Quote:        result.Quote,
+		QuoteMD5Sum:  db.Md5Sum(md5Sum[:]),
+		StartLine:    result.Start.Line,
+		EndLine:      result.End.Line,
+		Message:      result.Message,
+		ExtraInfo:    result.ExtraInfo,
+		Fingerprint:  result.Fingerprint,
+		DuplicateOf:  result.DuplicateOf,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error persisting finding: %w", err)
+	}
+	findingID, err := db.LastInsertID(bot.db) // TODO: not this;
+	if err != nil {
+		return 0, fmt.Errorf("error retrieving finding ID: %w", err)
+	}
+
+	if issue == nil {
+		return int64(findingID), nil
+	}
+	_, err = db.IssueDAO.Upsert(context.Background(), bot.db, db.Issue{
+		FindingID:   findingID,
+		GithubOwner: owner,
+		GithubRepo:  repo,
+		GithubID:    issue.GetNumber(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error persisting issue: %w", err)
+	}
+	return int64(findingID), nil
+}
+
+// JSONLinesSink writes each finding as a single line of JSON. It does not record findings in the database, so
+// near-duplicates of the findings it writes are not linked to them; they share the same fingerprint instead.
+type JSONLinesSink struct {
+	enc *json.Encoder
+}
+
+// NewJSONLinesSink creates a sink which writes to the provided writer.
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{enc: json.NewEncoder(w)}
+}
+
+// jsonFinding is the format of each line written by the JSONLinesSink.
+type jsonFinding struct {
+	Owner        string   `json:"owner"`
+	Repo         string   `json:"repo"`
+	FilePath     string   `json:"file_path"`
+	RootCommitID string   `json:"root_commit_id,omitempty"`
+	StartLine    int      `json:"start_line"`
+	EndLine      int      `json:"end_line"`
+	Message      string   `json:"message"`
+	Quote        string   `json:"quote"`
+	QuoteMD5Sum  string   `json:"quote_md5sum"`
+	ExtraInfo    string   `json:"extra_info,omitempty"`
+	Analyzer     string   `json:"analyzer,omitempty"`
+	SubFindings  []string `json:"sub_findings,omitempty"`
+	SuggestedFix string   `json:"suggested_fix,omitempty"`
+	Class        string   `json:"class"`
+	Fingerprint  string   `json:"fingerprint,omitempty"`
+	DuplicateOf  int64    `json:"duplicate_of,omitempty"`
+}
+
+// Consume writes the VetResult as a line of JSON.
+func (js *JSONLinesSink) Consume(result VetResult, md5Sum Md5Checksum) (int64, error) {
+	return 0, js.enc.Encode(jsonFinding{
+		Owner:        result.Owner,
+		Repo:         result.Repo,
+		FilePath:     result.FilePath,
+		RootCommitID: result.RootCommitID,
+		StartLine:    result.Start.Line,
+		EndLine:      result.End.Line,
+		Message:      result.Message,
+		Quote:        result.Quote,
+		QuoteMD5Sum:  fmt.Sprintf("%x", md5Sum),
+		ExtraInfo:    result.ExtraInfo,
+		Analyzer:     result.Analyzer,
+		SubFindings:  subFindingMessages(result),
+		SuggestedFix: result.SuggestedFix,
+		Class:        result.Class.String(),
+		Fingerprint:  fmt.Sprintf("%x", result.Fingerprint),
+		DuplicateOf:  result.DuplicateOf,
+	})
+}
+
+// FinishRepository is a no-op.
+func (js *JSONLinesSink) FinishRepository(repo Repository) error {
+	return nil
+}
+
+func subFindingMessages(result VetResult) []string {
+	var messages []string
+	for _, sub := range result.SubFindings {
+		messages = append(messages, sub.Message)
+	}
+	return messages
+}
+
+// Close is a no-op.
+func (js *JSONLinesSink) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var sinkTestResult = VetResult{
+	Repository:   Repository{Owner: "owner", Repo: "repo"},
+	FilePath:     "pkg/foo.go",
+	RootCommitID: "rootcommitid",
+	Quote:        "quote",
+	Start:        token.Position{Filename: "pkg/foo.go", Line: 3},
+	End:          token.Position{Filename: "pkg/foo.go", Line: 7},
+	Message:      "message",
+	Analyzer:     "looppointer",
+}
+
+func TestJSONLinesSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONLinesSink(&buf)
+	id, err := sink.Consume(sinkTestResult, md5.Sum([]byte("quote")))
+	assert.NoError(t, err)
+	assert.Zero(t, id, "JSON lines are not recorded in the database")
+	_, err = sink.Consume(sinkTestResult, md5.Sum([]byte("quote")))
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Close())
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+	var decoded jsonFinding
+	assert.NoError(t, json.Unmarshal(lines[0], &decoded))
+	assert.Equal(t, "owner", decoded.Owner)
+	assert.Equal(t, "pkg/foo.go", decoded.FilePath)
+	assert.Equal(t, 3, decoded.StartLine)
+	assert.Equal(t, 7, decoded.EndLine)
+	assert.Equal(t, "message", decoded.Message)
+	assert.Equal(t, "normal", decoded.Class)
+}
+
+func TestJSONLinesSinkNearDuplicate(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewJSONLinesSink(&buf)
+	result := sinkTestResult
+	result.Fingerprint = []byte{0xab, 0xcd}
+	result.DuplicateOf = 12
+	_, err := sink.Consume(result, md5.Sum([]byte("quote")))
+	assert.NoError(t, err)
+	_, err = sink.Consume(sinkTestResult, md5.Sum([]byte("quote")))
+	assert.NoError(t, err)
+	assert.NoError(t, sink.Close())
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	assert.Len(t, lines, 2)
+	var decoded jsonFinding
+	assert.NoError(t, json.Unmarshal(lines[0], &decoded))
+	assert.Equal(t, "abcd", decoded.Fingerprint)
+	assert.EqualValues(t, 12, decoded.DuplicateOf)
+	assert.NotContains(t, string(lines[1]), "fingerprint")
+	assert.NotContains(t, string(lines[1]), "duplicate_of")
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+)
+
+// Source provides access to the files of a repository which is about to be vetted.
+type Source interface {
+	// ReadFiles calls visit with the path and contents of every regular file in the source for which include
+	// returns true. Paths are slash-separated and relative to the root of the repository.
+	ReadFiles(include func(path string) bool, visit func(path string, contents []byte)) error
+}
+
+// GithubTarballSource reads the default branch of a GitHub repository by downloading it as a tarball.
+type GithubTarballSource struct {
+	bot  *VetBot
+	repo Repository
+}
+
+// NewGithubTarballSource creates a source which downloads the provided repository using the bot's client.
+func NewGithubTarballSource(bot *VetBot, repo Repository) *GithubTarballSource {
+	return &GithubTarballSource{bot: bot, repo: repo}
+}
+
+// ReadFiles downloads the tarball of the repository and reads each file it contains.
+func (s *GithubTarballSource) ReadFiles(include func(path string) bool, visit func(path string, contents []byte)) error {
+	url, _, err := s.bot.client.GetArchiveLink(s.repo.Owner, s.repo.Repo, github.Tarball, nil, false)
+	if err != nil {
+		log.Printf("failed to get tar link for %s/%s: %v", s.repo.Owner, s.repo.Repo, err)
+		return err
+	}
+	resp, err := http.Get(url.String())
+	if err != nil {
+		log.Printf("failed to download tar contents: %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+	log.Printf("reading contents of %s/%s", s.repo.Owner, s.repo.Repo)
+	return readTarball(resp.Body, include, visit)
+}
+
+// LocalTarballSource reads a .tar.gz file saved to disk. The tarball is expected to have the same layout
+// as the archives produced by GitHub, i.e. a single top-level directory containing the repository.
+type LocalTarballSource struct {
+	Path string
+}
+
+// ReadFiles opens the tarball and reads each file it contains.
+func (s *LocalTarballSource) ReadFiles(include func(path string) bool, visit func(path string, contents []byte)) error {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	log.Printf("reading contents of %s", s.Path)
+	return readTarball(file, include, visit)
+}
+
+// LocalDirSource reads a repository which has already been checked out to a local directory.
+type LocalDirSource struct {
+	Root string
+}
+
+// ReadFiles walks the directory tree and reads each file it contains. Any .git directories are skipped.
+func (s *LocalDirSource) ReadFiles(include func(path string) bool, visit func(path string, contents []byte)) error {
+	log.Printf("reading contents of %s", s.Root)
+	return filepath.Walk(s.Root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !include(rel) {
+			return nil
+		}
+		bytes, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Printf("error reading contents of %s: %v", rel, err)
+			return nil
+		}
+		visit(rel, bytes)
+		return nil
+	})
+}
+
+// NewLocalSource creates a Source for the provided path, which must refer either to a directory or to a
+// .tar.gz file.
+func NewLocalSource(path string) (Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &LocalDirSource{Root: path}, nil
+	}
+	if strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz") {
+		return &LocalTarballSource{Path: path}, nil
+	}
+	return nil, fmt.Errorf("%s is neither a directory nor a .tar.gz file", path)
+}
+
+// readTarball reads a gzipped tarball from the provided reader, stripping the top-level directory from the
+// name of each file it contains.
+func readTarball(r io.Reader, include func(path string) bool, visit func(path string, contents []byte)) error {
+	unzipped, err := gzip.NewReader(r)
+	if err != nil {
+		log.Printf("unable to initialize unzip stream: %v", err)
+		return err
+	}
+	reader := tar.NewReader(unzipped)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("failed to read tar entry")
+			return err
+		}
+		name := header.Name
+		split := strings.SplitN(name, "/", 2)
+		if len(split) < 2 {
+			continue // we only care about files in a subdirectory (due to how GitHub returns archives).
+		}
+		realName := split[1]
+		if header.Typeflag != tar.TypeReg || !include(realName) {
+			continue
+		}
+		bytes, err := ioutil.ReadAll(reader)
+		if err != nil {
+			log.Printf("error reading contents of %s: %v", realName, err)
+			continue
+		}
+		visit(realName, bytes)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalDirSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vetbot-source")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg", "a"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pkg", "a", "a.go"), []byte("package a"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("readme"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".git", "x.go"), []byte("package x"), 0644))
+
+	src, err := NewLocalSource(dir)
+	assert.NoError(t, err)
+	found := readAll(t, src)
+	assert.Equal(t, map[string]string{
+		"main.go":    "package main",
+		"pkg/a/a.go": "package a",
+	}, found)
+}
+
+func TestLocalTarballSource(t *testing.T) {
+	var buf bytes.Buffer
+	zipped := gzip.NewWriter(&buf)
+	writer := tar.NewWriter(zipped)
+	for name, contents := range map[string]string{
+		"owner-repo-abc123/main.go":    "package main",
+		"owner-repo-abc123/pkg/a/a.go": "package a",
+		"owner-repo-abc123/README.md":  "readme",
+	} {
+		assert.NoError(t, writer.WriteHeader(&tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+		}))
+		_, err := writer.Write([]byte(contents))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, zipped.Close())
+
+	dir, err := ioutil.TempDir("", "vetbot-source")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "repo.tar.gz")
+	assert.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+
+	src, err := NewLocalSource(path)
+	assert.NoError(t, err)
+	found := readAll(t, src)
+	assert.Equal(t, map[string]string{
+		"main.go":    "package main",
+		"pkg/a/a.go": "package a",
+	}, found)
+}
+
+func TestLocalSourceRejectsOtherFiles(t *testing.T) {
+	file, err := ioutil.TempFile("", "vetbot-source*.zip")
+	assert.NoError(t, err)
+	file.Close()
+	defer os.Remove(file.Name())
+
+	_, err = NewLocalSource(file.Name())
+	assert.Error(t, err)
+}
+
+func readAll(t *testing.T, src Source) map[string]string {
+	found := make(map[string]string)
+	err := src.ReadFiles(func(path string) bool {
+		return !IgnoreFile(path)
+	}, func(path string, contents []byte) {
+		found[path] = string(contents)
+	})
+	assert.NoError(t, err)
+	return found
+}
@@ -1,8 +1,12 @@
 // Package stats implements a global statistics store for instrumenting code to count the
-// occurrence of important events. The stats store is not thread-safe in the slightest.
+// occurrence of important events. The stats store is safe for concurrent use, so that analyzers
+// can be run by drivers which analyze several packages at once.
 package stats
 
-import "strings"
+import (
+	"strings"
+	"sync"
+)
 
 var statsStore statsStorage = statsStorage{
 	filenames:  make(map[string]struct{}),
@@ -10,40 +14,46 @@ var statsStore statsStorage = statsStorage{
 }
 
 type statsStorage struct {
+	mut        sync.Mutex
 	countStats map[CountStat]int
 	filenames  map[string]struct{}
 }
 
 // Clear resets all stores statistics to zero.
 func Clear() {
+	statsStore.mut.Lock()
+	defer statsStore.mut.Unlock()
 	statsStore.filenames = make(map[string]struct{})
 	statsStore.countStats = make(map[CountStat]int)
 }
 
 // AddCount adds the provided diff to the count of the provided CountStat
 func AddCount(stat CountStat, diff int) {
+	statsStore.mut.Lock()
+	defer statsStore.mut.Unlock()
 	statsStore.countStats[stat] += diff
 }
 
 // GetCount retrieves the current count of the provided CountStat so far.
 func GetCount(stat CountStat) int {
+	statsStore.mut.Lock()
+	defer statsStore.mut.Unlock()
 	return statsStore.countStats[stat]
 }
 
-// AddFile counts the existence of a file and updates the values of StatFiles and StatTestFiles
+// AddFile counts the existence of a file and updates the value of StatFiles. Test and vendored files are counted by
+// the caller, which knows how each file is classified.
 func AddFile(filename string) {
+	statsStore.mut.Lock()
+	defer statsStore.mut.Unlock()
 	statsStore.filenames[filename] = struct{}{}
 	statsStore.countStats[StatFiles]++
-	if strings.HasSuffix(filename, "_test.go") {
-		statsStore.countStats[StatTestFile]++
-	}
-	if strings.HasPrefix(filename, "vendor") {
-		statsStore.countStats[StatVendoredFile]++
-	}
 }
 
 // CountMissingTestFiles counts the number of files which don't have an associated test.
 func CountMissingTestFiles() int {
+	statsStore.mut.Lock()
+	defer statsStore.mut.Unlock()
 	result := 0
 	for filename := range statsStore.filenames {
 		if strings.HasSuffix(filename, ".pb.go") {
@@ -82,6 +92,24 @@ const (
 	StatLooppointerReportsThirdParty
 	StatLooppointerReportsPointerReassigned
 	StatLooppointerReportsCompositeLit
+	StatPackagesTypeChecked
+	StatPackagesTypeCheckFailed
+	StatLoopclosureSuppressedGo122
+	StatLooppointerSuppressedGo122
+	StatForLoops
+	StatLoopclosureForLoopHits
+	StatLooppointerForLoopHits
+	StatLooppointerReportsChanSend
+	StatLooppointerReportsAppend
+	StatLooppointerReportsIndexStore
+	StatLooppointerReportsReturn
+	StatLooppointerReportsClosureVar
+	StatPackidAmbiguousImports
+	StatAcceptListAmbiguousCalls
+	StatGeneratedFile
+	StatSlocGenerated
+	StatExampleFile
+	StatSlocExample
 )
 
 func (c CountStat) String() string {
@@ -126,6 +154,42 @@ func (c CountStat) String() string {
 		return "StatLooppointerReportsPointerReassigned"
 	case StatLooppointerReportsCompositeLit:
 		return "StatLooppointerReportsCompositeLit"
+	case StatPackagesTypeChecked:
+		return "StatPackagesTypeChecked"
+	case StatPackagesTypeCheckFailed:
+		return "StatPackagesTypeCheckFailed"
+	case StatLoopclosureSuppressedGo122:
+		return "StatLoopclosureSuppressedGo122"
+	case StatLooppointerSuppressedGo122:
+		return "StatLooppointerSuppressedGo122"
+	case StatForLoops:
+		return "StatForLoops"
+	case StatLoopclosureForLoopHits:
+		return "StatLoopclosureForLoopHits"
+	case StatLooppointerForLoopHits:
+		return "StatLooppointerForLoopHits"
+	case StatLooppointerReportsChanSend:
+		return "StatLooppointerReportsChanSend"
+	case StatLooppointerReportsAppend:
+		return "StatLooppointerReportsAppend"
+	case StatLooppointerReportsIndexStore:
+		return "StatLooppointerReportsIndexStore"
+	case StatLooppointerReportsReturn:
+		return "StatLooppointerReportsReturn"
+	case StatLooppointerReportsClosureVar:
+		return "StatLooppointerReportsClosureVar"
+	case StatPackidAmbiguousImports:
+		return "StatPackidAmbiguousImports"
+	case StatAcceptListAmbiguousCalls:
+		return "StatAcceptListAmbiguousCalls"
+	case StatGeneratedFile:
+		return "StatGeneratedFile"
+	case StatSlocGenerated:
+		return "StatSlocGenerated"
+	case StatExampleFile:
+		return "StatExampleFile"
+	case StatSlocExample:
+		return "StatSlocExample"
 	}
 	return "Unknown CountStat"
 }
@@ -150,5 +214,23 @@ var AllStats []CountStat = []CountStat{
 	StatLooppointerReportsAsync,
 	StatLooppointerReportsThirdParty,
 	StatLooppointerReportsPointerReassigned,
-	StatLooppointerReportsCompositeLit, // N.B. this is append only; rearranging the stats will result in corrupted data.
+	StatLooppointerReportsCompositeLit,
+	StatPackagesTypeChecked,
+	StatPackagesTypeCheckFailed,
+	StatLoopclosureSuppressedGo122,
+	StatLooppointerSuppressedGo122,
+	StatForLoops,
+	StatLoopclosureForLoopHits,
+	StatLooppointerForLoopHits,
+	StatLooppointerReportsChanSend,
+	StatLooppointerReportsAppend,
+	StatLooppointerReportsIndexStore,
+	StatLooppointerReportsReturn,
+	StatLooppointerReportsClosureVar,
+	StatPackidAmbiguousImports,
+	StatAcceptListAmbiguousCalls,
+	StatGeneratedFile,
+	StatSlocGenerated,
+	StatExampleFile,
+	StatSlocExample, // N.B. this is append only; rearranging the stats will result in corrupted data.
 }
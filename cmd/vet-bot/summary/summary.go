@@ -0,0 +1,132 @@
+// Package summary stores facts about the functions declared in third-party packages, which are computed by running
+// the pointerescapes and nogofunc analyzers over their source. Summaries allow calls into third-party code to be
+// vetted without listing each safe function by hand in the accept list.
+package summary
+
+import (
+	"go/ast"
+	"io/ioutil"
+
+	"github.com/github-vet/bots/cmd/vet-bot/packid"
+	"gopkg.in/yaml.v2"
+)
+
+// GlobalSummary stores the summary of third-party functions used by analyzers. If it is nil, no summary is set.
+var GlobalSummary *Summary
+
+// Summary records facts about the functions declared in a set of third-party packages.
+type Summary struct {
+	// Packages maps package paths to the functions declared in each package. Functions are keyed by name, and
+	// methods by the name of their receiver's base type and the name of the method separated by a dot, such as
+	// "Buffer.Write".
+	Packages map[string]map[string]Func `yaml:"packages"`
+}
+
+// Func records facts about a single function or method.
+type Func struct {
+	// Arity is the number of parameters declared by the function.
+	Arity int `yaml:"arity"`
+	// SafePtrs lists the indices of pointer arguments which the function was proven not to store.
+	SafePtrs []int `yaml:"safe_ptrs,omitempty"`
+	// NoGoroutines is true if the function was proven not to start any goroutine which could capture its
+	// arguments.
+	NoGoroutines bool `yaml:"no_goroutines"`
+}
+
+// New creates an empty summary.
+func New() *Summary {
+	return &Summary{Packages: make(map[string]map[string]Func)}
+}
+
+// Add records facts about the function declared in the provided package under the provided key.
+func (s *Summary) Add(pkg, key string, fn Func) {
+	if _, ok := s.Packages[pkg]; !ok {
+		s.Packages[pkg] = make(map[string]Func)
+	}
+	s.Packages[pkg][key] = fn
+}
+
+// Merge adds every function found in other to this summary, replacing any functions found in both.
+func (s *Summary) Merge(other *Summary) {
+	for pkg, funcs := range other.Packages {
+		for key, fn := range funcs {
+			s.Add(pkg, key, fn)
+		}
+	}
+}
+
+// Unmarshal unmarshals a Summary from a YAML file.
+func Unmarshal(data []byte) (*Summary, error) {
+	result := New()
+	if err := yaml.Unmarshal(data, result); err != nil {
+		return nil, err
+	}
+	if result.Packages == nil {
+		result.Packages = make(map[string]map[string]Func)
+	}
+	return result, nil
+}
+
+// Marshal marshals the summary into YAML.
+func (s *Summary) Marshal() ([]byte, error) {
+	return yaml.Marshal(s)
+}
+
+// FromFile reads a summary from the provided file.
+func FromFile(path string) (*Summary, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal(data)
+}
+
+// Load loads the GlobalSummary from the provided file path. If any errors occur, they are returned.
+func Load(path string) error {
+	summary, err := FromFile(path)
+	if err == nil {
+		GlobalSummary = summary
+	}
+	return err
+}
+
+// SafeArg returns true iff the provided callExpr calls a summarized third-party function which does not start any
+// goroutines and does not store the pointer passed as its argument at index idx.
+func SafeArg(pr *packid.PackageResolver, callExpr *ast.CallExpr, stack []ast.Node, idx int) bool {
+	if GlobalSummary == nil || callExpr == nil {
+		return false
+	}
+	pkg, name, err := pr.QualifiedCallee(callExpr, stack)
+	if err != nil {
+		return false
+	}
+	fn, ok := GlobalSummary.Packages[pkg][name]
+	if !ok || !fn.NoGoroutines || idx >= fn.Arity {
+		return false
+	}
+	for _, safe := range fn.SafePtrs {
+		if safe == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// Flag is a flag.Value which loads the GlobalSummary from the path it is set to.
+type Flag struct {
+	path string
+}
+
+// String returns the path of the summary which was loaded.
+func (f *Flag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.path
+}
+
+// Set loads the summary from the provided path.
+func (f *Flag) Set(path string) error {
+	f.path = path
+	return Load(path)
+}
@@ -0,0 +1,96 @@
+package summary
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/github-vet/bots/cmd/vet-bot/driver"
+	"github.com/github-vet/bots/cmd/vet-bot/packid"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
+)
+
+func TestFromFile(t *testing.T) {
+	summary, err := FromFile("testdata/summary.yaml")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	assert.Equal(t, Func{Arity: 2, SafePtrs: []int{1}, NoGoroutines: true}, summary.Packages["encoding/json"]["Unmarshal"])
+	assert.Contains(t, summary.Packages["example.com/cache"], "Cache.Get")
+	assert.False(t, summary.Packages["example.com/cache"]["Spawn"].NoGoroutines)
+}
+
+func TestMarshal(t *testing.T) {
+	summary := New()
+	summary.Add("example.com/lib", "Read", Func{Arity: 1, SafePtrs: []int{0}, NoGoroutines: true})
+	data, err := summary.Marshal()
+	assert.NoError(t, err)
+	roundTrip, err := Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, summary, roundTrip)
+}
+
+func TestMerge(t *testing.T) {
+	summary := New()
+	summary.Add("example.com/lib", "Read", Func{Arity: 1})
+	other := New()
+	other.Add("example.com/lib", "Read", Func{Arity: 1, NoGoroutines: true})
+	other.Add("example.com/other", "Write", Func{Arity: 2})
+	summary.Merge(other)
+	assert.True(t, summary.Packages["example.com/lib"]["Read"].NoGoroutines)
+	assert.Contains(t, summary.Packages["example.com/other"], "Write")
+}
+
+func TestSafeArg(t *testing.T) {
+	defer func() { GlobalSummary = nil }()
+	assert.NoError(t, Load("testdata/summary.yaml"))
+
+	src := `package p
+
+import (
+	"encoding/json"
+	"example.com/cache"
+)
+
+func f(data []byte, v *int) {
+	json.Unmarshal(data, v)
+	cache.Put("key", v)
+	cache.Spawn(v)
+	json.Marshal(v)
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, 0)
+	assert.NoError(t, err)
+	outcomes, err := driver.Run(fset, []*ast.File{file}, nil, []*analysis.Analyzer{packid.Analyzer}, func(analysis.Diagnostic) {})
+	assert.NoError(t, err)
+	pr := outcomes[len(outcomes)-1].Result.(*packid.PackageResolver)
+
+	var calls []*ast.CallExpr
+	ast.Inspect(file, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			calls = append(calls, call)
+		}
+		return true
+	})
+	stack := []ast.Node{file}
+	assert.True(t, SafeArg(pr, calls[0], stack, 1))  // json.Unmarshal(data, v)
+	assert.False(t, SafeArg(pr, calls[0], stack, 0)) // data is not a safe pointer argument
+	assert.False(t, SafeArg(pr, calls[1], stack, 1)) // cache.Put stores v
+	assert.False(t, SafeArg(pr, calls[2], stack, 0)) // cache.Spawn may start a goroutine
+	assert.False(t, SafeArg(pr, calls[3], stack, 0)) // json.Marshal is not summarized
+	assert.False(t, SafeArg(pr, &ast.CallExpr{}, nil, 0))
+}
+
+func TestFlag(t *testing.T) {
+	defer func() { GlobalSummary = nil }()
+	var f Flag
+	assert.NoError(t, f.Set("testdata/summary.yaml"))
+	assert.Equal(t, "testdata/summary.yaml", f.String())
+	if assert.NotNil(t, GlobalSummary) {
+		assert.Contains(t, GlobalSummary.Packages, "encoding/json")
+	}
+	assert.Error(t, f.Set("testdata/missing.yaml"))
+}
@@ -0,0 +1,273 @@
+// Package typecheck runs the type-checker over the files of a repository. Imports are resolved from the standard
+// library first. Other imports are resolved from source; either from a vendor directory, or from a package belonging
+// to one of the modules whose go.mod files are found in the repository. The standard library is imported via the
+// source importer, which requires the Go source tree to be available.
+package typecheck
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/github-vet/bots/cmd/vet-bot/gomod"
+)
+
+// stdImporter imports packages from the standard library. It is shared between calls to Check so that the standard
+// library is only type-checked once.
+var stdImporter = &lockedImporter{imp: importer.ForCompiler(token.NewFileSet(), "source", nil)}
+
+type lockedImporter struct {
+	mut sync.Mutex
+	imp types.Importer
+}
+
+func (li *lockedImporter) Import(importPath string) (*types.Package, error) {
+	li.mut.Lock()
+	defer li.mut.Unlock()
+	return li.imp.Import(importPath)
+}
+
+// Result describes the outcome of type-checking a repository.
+type Result struct {
+	// Info contains type information for every package which type-checked cleanly. Files from packages which failed
+	// to type-check have no entries in Info.
+	Info *types.Info
+	// Checked is the number of packages which type-checked cleanly.
+	Checked int
+	// Failed is the number of packages which failed to type-check.
+	Failed int
+}
+
+// Check type-checks every package found in the provided files. Files are grouped into packages by their directory
+// and package clause. Test files and files excluded by build constraints for the current platform are skipped.
+// contents maps the name of each file to its contents, and is used to evaluate build constraints. goMods maps the
+// slash-separated path of each go.mod file found in the repository to its contents, and is used to resolve imports of
+// packages found in the repository.
+func Check(fset *token.FileSet, files []*ast.File, contents map[string][]byte, goMods map[string][]byte) Result {
+	c := &checker{
+		fset:  fset,
+		info:  newInfo(),
+		units: make(map[string][]*unit),
+	}
+	for filename, data := range goMods {
+		mod := gomod.Parse(data)
+		mod.Dir = path.Dir(filename)
+		c.modules = append(c.modules, mod)
+	}
+	ctxt := build.Default
+	ctxt.OpenFile = func(name string) (io.ReadCloser, error) {
+		data, ok := contents[name]
+		if !ok {
+			return nil, fmt.Errorf("file %s not found", name)
+		}
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	ctxt.JoinPath = path.Join
+
+	for _, file := range files {
+		filename := fset.File(file.Pos()).Name()
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		dir, base := path.Split(filename)
+		dir = path.Clean(dir)
+		if ok, err := ctxt.MatchFile(dir, base); err != nil || !ok {
+			continue
+		}
+		u := c.unitFor(dir, file.Name.Name)
+		u.files = append(u.files, file)
+	}
+
+	result := Result{Info: c.info}
+	for _, units := range c.units {
+		for _, u := range units {
+			c.check(u)
+			if u.err != nil {
+				result.Failed++
+			} else {
+				result.Checked++
+			}
+		}
+	}
+	return result
+}
+
+// unit is a package found in a single directory of the repository. A directory may contain several packages, e.g. a
+// main package alongside tools written in another package.
+type unit struct {
+	dir      string
+	name     string
+	files    []*ast.File
+	pkg      *types.Package
+	err      error
+	checked  bool
+	checking bool
+}
+
+type checker struct {
+	fset    *token.FileSet
+	info    *types.Info
+	units   map[string][]*unit // units found in each directory
+	modules []*gomod.Module
+}
+
+// unitFor returns the unit for the package with the provided name found in dir, creating it if needed.
+func (c *checker) unitFor(dir, name string) *unit {
+	for _, u := range c.units[dir] {
+		if u.name == name {
+			return u
+		}
+	}
+	u := &unit{dir: dir, name: name}
+	c.units[dir] = append(c.units[dir], u)
+	return u
+}
+
+// importable returns the unit which is imported from the provided directory, or nil if the directory contains no
+// package which can be imported. If the directory contains several packages, the package named after the directory
+// is preferred, followed by the package with the most files.
+func (c *checker) importable(dir string) *unit {
+	var candidates []*unit
+	for _, u := range c.units[dir] {
+		if u.name != "main" {
+			candidates = append(candidates, u)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iNamed, jNamed := candidates[i].name == path.Base(dir), candidates[j].name == path.Base(dir)
+		if iNamed != jNamed {
+			return iNamed
+		}
+		if len(candidates[i].files) != len(candidates[j].files) {
+			return len(candidates[i].files) > len(candidates[j].files)
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	return candidates[0]
+}
+
+// unitImporter imports packages on behalf of a single unit, so that vendor directories can be resolved relative to the
+// directory of the importing package.
+type unitImporter struct {
+	c    *checker
+	from *unit
+}
+
+// Import imports a package, looking first in the standard library and then in the repository. Only import paths
+// whose first element contains no dot are looked up in the standard library.
+func (imp *unitImporter) Import(importPath string) (*types.Package, error) {
+	var stdErr error
+	if first := strings.SplitN(importPath, "/", 2)[0]; !strings.Contains(first, ".") {
+		var pkg *types.Package
+		pkg, stdErr = stdImporter.Import(importPath)
+		if stdErr == nil {
+			return pkg, nil
+		}
+	}
+	if u := imp.c.findUnit(importPath, imp.from.dir); u != nil {
+		imp.c.check(u)
+		return u.pkg, u.err
+	}
+	if stdErr != nil {
+		return nil, stdErr
+	}
+	return nil, fmt.Errorf("package %s not found in the repository", importPath)
+}
+
+// findUnit finds the package in the repository with the provided import path, as imported from the package found in
+// fromDir. Vendor directories found in fromDir or any of its parents are searched first, innermost first. Otherwise,
+// the package is found in the module with the longest module path matching the import path.
+func (c *checker) findUnit(importPath, fromDir string) *unit {
+	for dir := fromDir; ; dir = path.Dir(dir) {
+		if u := c.importable(path.Join(dir, "vendor", importPath)); u != nil {
+			return u
+		}
+		if dir == "." || dir == "/" || dir == "" {
+			break
+		}
+	}
+	var best *gomod.Module
+	var bestDir string
+	for _, mod := range c.modules {
+		dir, ok := mod.DirFor(importPath)
+		if !ok {
+			continue
+		}
+		if best == nil || len(mod.Path) > len(best.Path) {
+			best, bestDir = mod, dir
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return c.importable(bestDir)
+}
+
+// check type-checks the provided unit, merging its type information into the checker's if it succeeds.
+func (c *checker) check(u *unit) {
+	if u.checked {
+		return
+	}
+	if u.checking {
+		u.err = fmt.Errorf("import cycle through %s", u.dir)
+		return
+	}
+	u.checking = true
+	defer func() {
+		u.checking = false
+		u.checked = true
+	}()
+
+	info := newInfo()
+	conf := types.Config{Importer: &unitImporter{c: c, from: u}}
+	u.pkg, u.err = conf.Check(u.dir, c.fset, u.files, info)
+	if u.err != nil {
+		return
+	}
+	mergeInfo(c.info, info)
+}
+
+func newInfo() *types.Info {
+	return &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Implicits:  make(map[ast.Node]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+		Scopes:     make(map[ast.Node]*types.Scope),
+	}
+}
+
+func mergeInfo(dst, src *types.Info) {
+	for k, v := range src.Types {
+		dst.Types[k] = v
+	}
+	for k, v := range src.Defs {
+		dst.Defs[k] = v
+	}
+	for k, v := range src.Uses {
+		dst.Uses[k] = v
+	}
+	for k, v := range src.Implicits {
+		dst.Implicits[k] = v
+	}
+	for k, v := range src.Selections {
+		dst.Selections[k] = v
+	}
+	for k, v := range src.Scopes {
+		dst.Scopes[k] = v
+	}
+}
@@ -0,0 +1,94 @@
+package typecheck
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseFiles(t *testing.T, sources map[string]string) (*token.FileSet, []*ast.File, map[string][]byte) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	contents := make(map[string][]byte)
+	for name, src := range sources {
+		file, err := parser.ParseFile(fset, name, src, 0)
+		assert.NoError(t, err)
+		files = append(files, file)
+		contents[name] = []byte(src)
+	}
+	return fset, files, contents
+}
+
+func TestCheck(t *testing.T) {
+	fset, files, contents := parseFiles(t, map[string]string{
+		"main.go":                       "package main\nimport \"github.com/owner/repo/sub\"\nfunc main() { sub.F(nil) }\n",
+		"sub/sub.go":                    "package sub\nimport \"example.com/dep\"\nfunc F(p *int) { dep.G() }\n",
+		"vendor/example.com/dep/dep.go": "package dep\nfunc G() {}\n",
+		"broken/broken.go":              "package broken\nfunc H() { undefined() }\n",
+		"sub/sub_test.go":               "package sub\nfunc TestF() { undefined() }\n",
+		"sub/sub_windows.go":            "// +build ignore\n\npackage sub\nfunc F(p *int) {}\n",
+	})
+	result := Check(fset, files, contents, map[string][]byte{"go.mod": []byte("module github.com/owner/repo\n")})
+	assert.Equal(t, 3, result.Checked)
+	assert.Equal(t, 1, result.Failed)
+
+	var call *ast.CallExpr
+	for _, file := range files {
+		if fset.File(file.Pos()).Name() != "main.go" {
+			continue
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			if c, ok := n.(*ast.CallExpr); ok {
+				call = c
+			}
+			return true
+		})
+	}
+	if assert.NotNil(t, call) {
+		sel := call.Fun.(*ast.SelectorExpr)
+		fn, ok := result.Info.Uses[sel.Sel].(*types.Func)
+		if assert.True(t, ok) {
+			assert.Equal(t, "sub.F", fn.FullName())
+		}
+	}
+}
+
+func TestCheckStandardLibraryFirst(t *testing.T) {
+	fset, files, contents := parseFiles(t, map[string]string{
+		"main.go":   "package main\nimport \"sort\"\nfunc main() { sort.Ints(nil) }\n",
+		"sort/x.go": "package sort\nfunc Local() {}\n",
+	})
+	result := Check(fset, files, contents, map[string][]byte{"go.mod": []byte("module github.com/owner/repo\n")})
+	assert.Equal(t, 2, result.Checked)
+	assert.Equal(t, 0, result.Failed)
+}
+
+func TestCheckOtherModule(t *testing.T) {
+	fset, files, contents := parseFiles(t, map[string]string{
+		"main.go":    "package main\nimport \"github.com/other/util\"\nfunc main() { util.F() }\n",
+		"util/x.go":  "package util\nfunc F() {}\n",
+		"mod/a/a.go": "package a\nimport \"example.com/nested/b\"\nfunc A() { b.B() }\n",
+		"mod/b/b.go": "package b\nfunc B() {}\n",
+	})
+	result := Check(fset, files, contents, map[string][]byte{
+		"go.mod":     []byte("module github.com/owner/repo\n"),
+		"mod/go.mod": []byte("module example.com/nested\n"),
+	})
+	assert.Equal(t, 3, result.Checked)
+	assert.Equal(t, 1, result.Failed, "packages from other repositories should not resolve to directories with the same name")
+}
+
+func TestCheckPackagesInSameDirectory(t *testing.T) {
+	fset, files, contents := parseFiles(t, map[string]string{
+		"cmd/main.go":  "package main\nfunc main() { run() }\n",
+		"cmd/run.go":   "package main\nfunc run() {}\n",
+		"cmd/tools.go": "package tools\nfunc Generate() {}\n",
+	})
+	result := Check(fset, files, contents, nil)
+	assert.Equal(t, 2, result.Checked)
+	assert.Equal(t, 0, result.Failed)
+}
@@ -4,6 +4,8 @@ import (
 	"go/token"
 	"testing"
 
+	"github.com/github-vet/bots/cmd/vet-bot/classify"
+	"github.com/github-vet/bots/cmd/vet-bot/stats"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,3 +44,76 @@ func TestDescriptionTemplate(t *testing.T) {
 	assert.Contains(t, description, "[file/path space/foo.go](https://github.com/owner/repo/blob/rootcommitid/file/path%20space/foo.go#L123-L125)")
 	assert.Contains(t, description, "[Click here to see the code in its original context.](https://github.com/owner/repo/blob/rootcommitid/file/path%20space/foo.go#L123-L125)")
 }
+
+func TestDescriptionTemplateSubFindings(t *testing.T) {
+	description := Description(VetResult{
+		Message: "first (and 1 more)",
+		SubFindings: []SubFinding{
+			{Message: "first"},
+			{Message: "second"},
+		},
+	})
+	assert.Contains(t, description, "> first (and 1 more)\n")
+	assert.Contains(t, description, "found 2 issues")
+	assert.Contains(t, description, "* first\n* second\n")
+}
+
+func TestDescriptionTemplateSuggestedFix(t *testing.T) {
+	description := Description(VetResult{
+		Message:      "message",
+		SuggestedFix: "--- a/foo.go\n+++ b/foo.go\n",
+	})
+	assert.Contains(t, description, "```diff\n--- a/foo.go\n+++ b/foo.go\n```")
+	assert.NotContains(t, Description(VetResult{Message: "message"}), "```diff")
+}
+
+func TestDuplicateComment(t *testing.T) {
+	comment := DuplicateComment(VetResult{
+		Repository:   Repository{Owner: "owner", Repo: "repo"},
+		FilePath:     "file/foo.go",
+		RootCommitID: "rootcommitid",
+		Message:      "message",
+		Start:        token.Position{Filename: "file/foo.go", Line: 3},
+		End:          token.Position{Line: 5},
+	})
+	assert.Contains(t, comment, "[owner/repo](https://www.github.com/owner/repo)")
+	assert.Contains(t, comment, "[file/foo.go](https://github.com/owner/repo/blob/rootcommitid/file/foo.go#L3-L5)")
+	assert.Contains(t, comment, "> message\n")
+}
+
+func TestLabelsAndState(t *testing.T) {
+	result := VetResult{
+		Start: token.Position{Line: 3},
+		End:   token.Position{Line: 7},
+	}
+	assert.Equal(t, []string{"fresh", "tiny"}, Labels(result))
+	assert.Equal(t, "open", State(result))
+
+	result.Class = classify.ClassGenerated
+	assert.Equal(t, []string{"fresh", "tiny", "generated"}, Labels(result))
+	assert.Equal(t, "open", State(result))
+
+	result.Class = classify.ClassTest
+	assert.Equal(t, []string{"fresh", "tiny", "test"}, Labels(result))
+	assert.Equal(t, "closed", State(result))
+}
+
+func TestUsesGithub(t *testing.T) {
+	assert.True(t, opts{Sink: SinkGithub}.usesGithub())
+	assert.True(t, opts{Sink: SinkJSONL}.usesGithub(), "repositories are downloaded from GitHub")
+	assert.True(t, opts{LocalPath: "src", Sink: SinkGithub}.usesGithub())
+	assert.False(t, opts{LocalPath: "src", Sink: SinkJSONL}.usesGithub())
+	assert.False(t, opts{LocalPath: "src", Sink: SinkDatabase}.usesGithub())
+}
+
+func TestCountLines(t *testing.T) {
+	stats.Clear()
+	countLines([]byte("package p\n\nfunc TestP() {}\n"), classify.ClassTest)
+	countLines([]byte("package q\n"), classify.ClassVendored)
+	countLines([]byte("package r\n"), classify.ClassNormal)
+	assert.EqualValues(t, 5, stats.GetCount(stats.StatSloc))
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatTestFile))
+	assert.EqualValues(t, 3, stats.GetCount(stats.StatSlocTest))
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatVendoredFile))
+	assert.EqualValues(t, 1, stats.GetCount(stats.StatSlocVendored))
+}
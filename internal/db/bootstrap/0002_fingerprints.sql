@@ -0,0 +1,5 @@
+-- fingerprints identify near-duplicate findings; see snippet.Fingerprint.
+ALTER TABLE findings ADD COLUMN fingerprint  BLOB NOT NULL DEFAULT x'';  -- empty if the quote was not fingerprinted
+ALTER TABLE findings ADD COLUMN duplicate_of INTEGER NOT NULL DEFAULT 0; -- id of the original finding; 0 if this is the original
+
+CREATE INDEX IF NOT EXISTS findings_fingerprint ON findings (fingerprint);
@@ -5,8 +5,10 @@ import (
 	"crypto/md5"
 	"database/sql"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/github-vet/bots/internal/db"
@@ -188,3 +190,129 @@ func TestFindingDAO(t *testing.T) {
 	assert.Equal(t, "message", f.Message)
 	assert.Equal(t, "extra", f.ExtraInfo)
 }
+
+func TestListByExpertAssessment(t *testing.T) {
+	ctx := context.Background()
+
+	hash := md5.Sum([]byte("confirmed"))
+	for i := 0; i < 3; i++ {
+		_, err := db.FindingDAO.Create(ctx, DB, db.Finding{
+			GithubOwner:  "confirmed",
+			GithubRepo:   "repo",
+			Filepath:     "filepath",
+			RootCommitID: "rootCommit",
+			Quote:        "confirmed",
+			QuoteMD5Sum:  hash[:],
+			StartLine:    i,
+			EndLine:      i + 1,
+		})
+		assert.NoError(t, err)
+		id, err := db.LastInsertID(DB)
+		assert.NoError(t, err)
+
+		issue := db.Issue{
+			FindingID:        id,
+			GithubOwner:      "tracker",
+			GithubRepo:       "issues",
+			GithubID:         100 + i,
+			ExpertAssessment: "-1",
+		}
+		issue.SetExpertsDisagree(i == 2)
+		if i == 1 {
+			issue.ExpertAssessment = "+1"
+		}
+		_, err = db.IssueDAO.Upsert(ctx, DB, issue)
+		assert.NoError(t, err)
+	}
+
+	findings, err := db.FindingDAO.ListByExpertAssessment(ctx, DB, "-1")
+	assert.NoError(t, err)
+	if assert.Len(t, findings, 1) {
+		assert.Equal(t, "confirmed", findings[0].GithubOwner)
+		assert.Equal(t, 0, findings[0].StartLine)
+
+		issue, err := db.IssueDAO.FindByFinding(ctx, DB, findings[0].ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 100, issue.GithubID)
+		assert.Equal(t, "tracker", issue.GithubOwner)
+	}
+}
+
+func TestListOriginals(t *testing.T) {
+	ctx := context.Background()
+
+	fingerprint := md5.Sum([]byte("fingerprint"))
+	create := func(owner string, fp db.Md5Sum, duplicateOf int64) int64 {
+		_, err := db.FindingDAO.Create(ctx, DB, db.Finding{
+			GithubOwner:  owner,
+			GithubRepo:   "repo",
+			Filepath:     "filepath",
+			RootCommitID: "rootCommit",
+			Quote:        owner,
+			QuoteMD5Sum:  db.Md5Sum(owner),
+			Fingerprint:  fp,
+			DuplicateOf:  duplicateOf,
+		})
+		assert.NoError(t, err)
+		id, err := db.LastInsertID(DB)
+		assert.NoError(t, err)
+		return int64(id)
+	}
+	originals := func() map[string]int64 {
+		findings, err := db.FindingDAO.ListOriginals(ctx, DB)
+		assert.NoError(t, err)
+		result := make(map[string]int64)
+		for _, f := range findings {
+			assert.Zero(t, f.DuplicateOf)
+			result[string(f.Fingerprint)] = f.ID
+		}
+		return result
+	}
+	unfingerprinted := create("unfingerprinted", nil, 0)
+	original := create("original", fingerprint[:], 0)
+	duplicate := create("duplicate", fingerprint[:], original)
+
+	assert.Equal(t, original, originals()[string(fingerprint[:])])
+
+	f, err := db.FindingDAO.FindByID(ctx, DB, duplicate)
+	assert.NoError(t, err)
+	assert.Equal(t, original, f.DuplicateOf)
+
+	other := md5.Sum([]byte("other"))
+	assert.NotContains(t, originals(), string(other[:]))
+
+	findings, err := db.FindingDAO.ListUnfingerprinted(ctx, DB)
+	assert.NoError(t, err)
+	var ids []int64
+	for _, f := range findings {
+		ids = append(ids, f.ID)
+	}
+	assert.Contains(t, ids, unfingerprinted)
+
+	count, err := db.FindingDAO.SetFingerprint(ctx, DB, unfingerprinted, other[:])
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	assert.Equal(t, unfingerprinted, originals()[string(other[:])])
+}
+
+func TestBootstrapDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bootstrap")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "0001_create.sql"), []byte("CREATE TABLE things (id INTEGER PRIMARY KEY);"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "0002_alter.sql"), []byte("ALTER TABLE things ADD COLUMN name TEXT;"), 0644))
+
+	testDB, err := sql.Open("sqlite3", filepath.Join(dir, "test.db"))
+	assert.NoError(t, err)
+	defer testDB.Close()
+
+	assert.NoError(t, db.BootstrapDB(dir, testDB))
+	// neither script can be executed twice; the second bootstrap must skip them both.
+	assert.NoError(t, db.BootstrapDB(dir, testDB))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "0003_insert.sql"), []byte("INSERT INTO things (name) VALUES ('thing');"), 0644))
+	assert.NoError(t, db.BootstrapDB(dir, testDB))
+	var count int
+	assert.NoError(t, testDB.QueryRow("SELECT COUNT(*) FROM things WHERE name = 'thing'").Scan(&count))
+	assert.Equal(t, 1, count)
+}
@@ -20,26 +20,41 @@ type Finding struct {
 	EndLine      int    `prof:"end_line"`
 	Message      string `prof:"message"`
 	ExtraInfo    string `prof:"extra_info"`
+	Fingerprint  Md5Sum `prof:"fingerprint"`  // identifies near-duplicates; empty if the quote was not fingerprinted
+	DuplicateOf  int64  `prof:"duplicate_of"` // the ID of the finding this nearly duplicates; 0 if it is the original
 }
 
 type Md5Sum []byte
 
 type FindingDaoImpl struct {
-	Create        func(ctx context.Context, e proteus.ContextExecutor, f Finding) (int64, error) `proq:"q:create" prop:"f"`
-	FindByID      func(ctx context.Context, q proteus.ContextQuerier, id int64) (Finding, error) `proq:"q:findById" prop:"id"`
-	ListChecksums func(ctx context.Context, q proteus.ContextQuerier) ([]Md5Sum, error)          `proq:"q:listChecksums"`
+	Create                 func(ctx context.Context, e proteus.ContextExecutor, f Finding) (int64, error)                    `proq:"q:create" prop:"f"`
+	FindByID               func(ctx context.Context, q proteus.ContextQuerier, id int64) (Finding, error)                    `proq:"q:findById" prop:"id"`
+	ListChecksums          func(ctx context.Context, q proteus.ContextQuerier) ([]Md5Sum, error)                             `proq:"q:listChecksums"`
+	ListByExpertAssessment func(ctx context.Context, q proteus.ContextQuerier, assessment string) ([]Finding, error)         `proq:"q:listByExpertAssessment" prop:"assessment"`
+	ListOriginals          func(ctx context.Context, q proteus.ContextQuerier) ([]Finding, error)                            `proq:"q:listOriginals"`
+	ListUnfingerprinted    func(ctx context.Context, q proteus.ContextQuerier) ([]Finding, error)                            `proq:"q:listUnfingerprinted"`
+	SetFingerprint         func(ctx context.Context, e proteus.ContextExecutor, id int64, fingerprint Md5Sum) (int64, error) `proq:"q:setFingerprint" prop:"id,fingerprint"`
 }
 
 var FindingDAO FindingDaoImpl
 
 func init() {
 	m := proteus.MapMapper{
-		"create": `INSERT INTO findings (github_repo, github_owner, quote_md5sum, filepath, root_commit_id, quote, quote_md5sum, start_line, end_line, message, extra_info) 
-							 VALUES (:f.GithubRepo:, :f.GithubOwner:, :f.QuoteMD5Sum:, :f.Filepath:, :f.RootCommitID:, :f.Quote:, :f.QuoteMD5Sum:, :f.StartLine:, :f.EndLine:, :f.Message:, :f.ExtraInfo:)`,
+		"create": `INSERT INTO findings (github_repo, github_owner, quote_md5sum, filepath, root_commit_id, quote, quote_md5sum, start_line, end_line, message, extra_info, fingerprint, duplicate_of) 
+							 VALUES (:f.GithubRepo:, :f.GithubOwner:, :f.QuoteMD5Sum:, :f.Filepath:, :f.RootCommitID:, :f.Quote:, :f.QuoteMD5Sum:, :f.StartLine:, :f.EndLine:, :f.Message:, :f.ExtraInfo:, COALESCE(:f.Fingerprint:, x''), :f.DuplicateOf:)`,
 
 		"findById":      `SELECT * FROM findings WHERE id = :id:`,
 		"listChecksums": `SELECT quote_md5sum FROM findings`,
 		"lastUpdateId":  `SELECT last_update_id()`,
+
+		"listByExpertAssessment": `SELECT findings.* FROM findings
+																 JOIN issues ON issues.finding_id = findings.id
+															 WHERE issues.expert_assessment = :assessment: AND
+																		 issues.expert_disagreement = 0`,
+
+		"listOriginals":       `SELECT * FROM findings WHERE length(fingerprint) > 0 AND duplicate_of = 0 ORDER BY id`,
+		"listUnfingerprinted": `SELECT * FROM findings WHERE length(fingerprint) = 0`,
+		"setFingerprint":      `UPDATE findings SET fingerprint = :fingerprint: WHERE id = :id:`,
 	}
 	err := proteus.ShouldBuild(context.Background(), &FindingDAO, proteus.Sqlite, m)
 	if err != nil {
@@ -30,6 +30,7 @@ func (i *Issue) SetExpertsDisagree(value bool) {
 type IssueDAOImpl struct {
 	FindByCoordinates func(ctx context.Context, q proteus.ContextQuerier, owner, repo string, githubID int) (Issue, error) `proq:"q:findByFindingID" prop:"owner,repo,githubID"`
 	Upsert            func(ctx context.Context, q proteus.ContextExecutor, i Issue) (int64, error)                         `proq:"q:upsert" prop:"i"`
+	FindByFinding     func(ctx context.Context, q proteus.ContextQuerier, findingID int64) (Issue, error)                  `proq:"q:findByFinding" prop:"findingID"`
 }
 
 var IssueDAO IssueDAOImpl
@@ -41,6 +42,8 @@ func init() {
 															github_repo = :repo: AND
 															github_id = :githubID:`,
 
+		"findByFinding": `SELECT * from issues WHERE finding_id = :findingID:`,
+
 		"upsert": `INSERT INTO issues (finding_id, github_owner, github_repo, github_id, expert_assessment, expert_disagreement)
 									VALUES (:i.FindingID:, :i.GithubOwner:, :i.GithubRepo:, :i.GithubID:, :i.ExpertAssessment:, :i.ExpertDisagreement:)
 								ON CONFLICT (finding_id) DO UPDATE
@@ -14,12 +14,18 @@ import (
 )
 
 // BootstrapDB attempts to execute all files ending in .sql from the provided directory against the
-// provided database, in alphabetical order by filename. If no files are found, an error is returned.
+// provided database, in alphabetical order by filename. The name of each file executed is recorded in the
+// schema_migrations table, and files which were already executed are skipped, so that files which alter existing
+// tables are only executed once. If no files are found, an error is returned.
 func BootstrapDB(schemaFolder string, DB *sql.DB) error {
 	files, err := ioutil.ReadDir(schemaFolder)
 	if err != nil {
 		return err
 	}
+	executed, err := executedScripts(DB)
+	if err != nil {
+		return fmt.Errorf("could not read executed bootstrap scripts: %w", err)
+	}
 	foundSQLFile := false
 	for _, finfo := range files {
 		if finfo.IsDir() {
@@ -29,12 +35,15 @@ func BootstrapDB(schemaFolder string, DB *sql.DB) error {
 			continue
 		}
 		foundSQLFile = true
+		if _, ok := executed[finfo.Name()]; ok {
+			continue
+		}
 
 		script, err := ioutil.ReadFile(filepath.Join(schemaFolder, finfo.Name()))
 		if err != nil {
 			return err
 		}
-		_, err = DB.Exec(string(script))
+		err = executeScript(DB, finfo.Name(), string(script))
 		if err != nil {
 			log.Printf("could not execute bootstrap script %s: %v", finfo.Name(), err)
 			return err
@@ -47,6 +56,46 @@ func BootstrapDB(schemaFolder string, DB *sql.DB) error {
 	return nil
 }
 
+// executedScripts creates the schema_migrations table if needed, and returns the name of every bootstrap script
+// which was executed against the provided database.
+func executedScripts(DB *sql.DB) (map[string]struct{}, error) {
+	_, err := DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (filename TEXT PRIMARY KEY)`)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := DB.Query(`SELECT filename FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[string]struct{})
+	for rows.Next() {
+		var filename string
+		if err := rows.Scan(&filename); err != nil {
+			return nil, err
+		}
+		result[filename] = struct{}{}
+	}
+	return result, rows.Err()
+}
+
+// executeScript executes the provided bootstrap script and records its name in a single transaction.
+func executeScript(DB *sql.DB, filename, script string) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(script); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (filename) VALUES (?)`, filename); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 // SeedRepositories loads the repositories from the provided CSV file into the database only
 // if there are not any repository records already present in the database.
 func SeedRepositories(repoCsv string, DB *sql.DB) error {
@@ -162,6 +162,57 @@ func (c *Client) GetRepositoryBranch(owner, repo, branch string) (*github.Branch
 	return b, resp, err
 }
 
+// GetContents fetches the contents of a file or directory in a repository.
+//
+// GitHub API docs: https://developer.github.com/v3/repos/contents/#get-repository-content
+func (c *Client) GetContents(owner, repo, path string, opt *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	c.blockOnLimit()
+	file, dir, resp, err := c.client.Repositories.GetContents(c.ctx, owner, repo, path, opt)
+	c.updateRateLimits(resp, err)
+	return file, dir, resp, err
+}
+
+// UpdateFile updates a file in a repository at the given path.
+//
+// GitHub API docs: https://developer.github.com/v3/repos/contents/#create-or-update-file-contents
+func (c *Client) UpdateFile(owner, repo, path string, opt *github.RepositoryContentFileOptions) (*github.RepositoryContentResponse, *github.Response, error) {
+	c.blockOnLimit()
+	content, resp, err := c.client.Repositories.UpdateFile(c.ctx, owner, repo, path, opt)
+	c.updateRateLimits(resp, err)
+	return content, resp, err
+}
+
+// CreateFork creates a fork of the specified repository. GitHub creates forks in the background, so this method may
+// return an *github.AcceptedError along with details of the pending fork.
+//
+// GitHub API docs: https://developer.github.com/v3/repos/forks/#create-a-fork
+func (c *Client) CreateFork(owner, repo string, opt *github.RepositoryCreateForkOptions) (*github.Repository, *github.Response, error) {
+	c.blockOnLimit()
+	fork, resp, err := c.client.Repositories.CreateFork(c.ctx, owner, repo, opt)
+	c.updateRateLimits(resp, err)
+	return fork, resp, err
+}
+
+// CreateRef creates a new ref in a repository.
+//
+// GitHub API docs: https://developer.github.com/v3/git/refs/#create-a-reference
+func (c *Client) CreateRef(owner, repo string, ref *github.Reference) (*github.Reference, *github.Response, error) {
+	c.blockOnLimit()
+	r, resp, err := c.client.Git.CreateRef(c.ctx, owner, repo, ref)
+	c.updateRateLimits(resp, err)
+	return r, resp, err
+}
+
+// CreatePullRequest creates a new pull request on the specified repository.
+//
+// GitHub API docs: https://developer.github.com/v3/pulls/#create-a-pull-request
+func (c *Client) CreatePullRequest(owner, repo string, pull *github.NewPullRequest) (*github.PullRequest, *github.Response, error) {
+	c.blockOnLimit()
+	pr, resp, err := c.client.PullRequests.Create(c.ctx, owner, repo, pull)
+	c.updateRateLimits(resp, err)
+	return pr, resp, err
+}
+
 var skew time.Duration = time.Second
 var minAbuseRetry time.Duration = 2 * time.Minute
 
@@ -0,0 +1,115 @@
+// Package snippet quotes the snippets of code recorded for each finding, so that they can be compared between the
+// bots which record findings and the bots which act upon them.
+package snippet
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// Quote retrieves the snippet of code found between lineStart and lineEnd (inclusive) of the provided file contents.
+// The snippet is formatted with gofmt if possible, so that it can be shown without its leading indentation.
+func Quote(contents []byte, lineStart, lineEnd int) string {
+	sc := bufio.NewScanner(bytes.NewReader(contents))
+	line := 0
+	var sb strings.Builder
+	for sc.Scan() && line < lineEnd {
+		line++
+		if lineStart == line { // truncate whitespace from the first line (fixes formatting later)
+			sb.WriteString(strings.TrimSpace(sc.Text()) + "\n")
+		}
+		if lineStart < line && line <= lineEnd {
+			sb.WriteString(sc.Text() + "\n")
+		}
+	}
+
+	// run go fmt on the snippet to remove leading whitespace
+	snippet := sb.String()
+	formatted, err := format.Source([]byte(snippet))
+	if err != nil {
+		return snippet
+	}
+	return string(formatted)
+}
+
+// MinFingerprintTokens is the number of tokens a snippet must contain to be fingerprinted. The shortest typical
+// finding, a range loop whose body starts a goroutine calling a single function, contains 21 tokens, whereas single
+// statements such as `ptrs = append(ptrs, &x)` contain around 10. Such short snippets are too generic to be considered
+// near-duplicates of one another merely because their tokens match.
+const MinFingerprintTokens = 20
+
+// Fingerprint returns the MD5 sum of a normalized stream of the tokens found in the provided snippet of Go code.
+// Whitespace, comments and semicolons are skipped, and each identifier declared within the snippet, such as a
+// range-loop variable or a parameter of a function literal, is replaced by the order in which it first appears.
+// Every other identifier, including package names, selectors and the names of functions declared elsewhere, is kept
+// verbatim, so that snippets which differ only by formatting, comments or consistently renamed local variables share
+// a fingerprint. Identifiers are only replaced if the snippet parses as a list of statements.
+// Fingerprint returns nil if the snippet contains fewer than MinFingerprintTokens tokens.
+func Fingerprint(quote string) []byte {
+	declared := declaredIdents(quote)
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(quote))
+	var s scanner.Scanner
+	s.Init(file, []byte(quote), nil, 0) // errors are ignored; snippets need not be complete
+	names := make(map[*ast.Object]int)
+	var b strings.Builder
+	count := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON {
+			continue // semicolons are inserted automatically depending on how the snippet is formatted.
+		}
+		count++
+		obj, isDeclared := declared[file.Offset(pos)]
+		switch {
+		case tok == token.IDENT && isDeclared:
+			if _, ok := names[obj]; !ok {
+				names[obj] = len(names)
+			}
+			fmt.Fprintf(&b, "$%d ", names[obj])
+		case tok == token.IDENT, tok.IsLiteral():
+			b.WriteString(lit + " ")
+		default:
+			b.WriteString(tok.String() + " ")
+		}
+	}
+	if count < MinFingerprintTokens {
+		return nil
+	}
+	sum := md5.Sum([]byte(b.String()))
+	return sum[:]
+}
+
+// declaredIdents parses the provided snippet as the body of a function, and returns the object declared by each
+// identifier which refers to a variable, constant, type or label declared within the snippet, keyed by its offset in
+// the snippet. It returns nil if the snippet can't be parsed.
+func declaredIdents(quote string) map[int]*ast.Object {
+	const prefix = "package p\nfunc _() {\n"
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, "", prefix+quote+"\n}\n", 0)
+	if err != nil || len(parsed.Decls) != 1 {
+		return nil
+	}
+	body := parsed.Decls[0].(*ast.FuncDecl).Body
+	result := make(map[int]*ast.Object)
+	ast.Inspect(body, func(n ast.Node) bool {
+		// the parser only resolves identifiers to objects declared in the same file, and the only object declared
+		// outside of the snippet is the function wrapping it, which it can't refer to by its blank name.
+		if id, ok := n.(*ast.Ident); ok && id.Obj != nil {
+			result[fset.Position(id.Pos()).Offset-len(prefix)] = id.Obj
+		}
+		return true
+	})
+	return result
+}
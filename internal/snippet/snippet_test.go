@@ -0,0 +1,81 @@
+package snippet_test
+
+import (
+	"testing"
+
+	"github.com/github-vet/bots/internal/snippet"
+	"github.com/stretchr/testify/assert"
+)
+
+const source = `package main
+
+func main() {
+	for _, x := range xs {
+		go func() {
+			println(x)
+		}()
+	}
+}
+`
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		start, end int
+		expected   string
+	}{
+		{4, 8, "for _, x := range xs {\n\tgo func() {\n\t\tprintln(x)\n\t}()\n}\n"},
+		{6, 6, "println(x)\n"},
+		{9, 12, "}\n"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, snippet.Quote([]byte(source), test.start, test.end))
+	}
+}
+
+const original = `for _, x := range xs {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		result := process(x)
+		log.Printf("processed %v", x)
+		done <- result
+	}()
+}
+`
+
+func TestFingerprint(t *testing.T) {
+	fingerprint := snippet.Fingerprint(original)
+	assert.Len(t, fingerprint, 16)
+
+	nearDuplicates := []string{
+		"for _, x := range xs {\n  wg.Add(1)\n  go func() {\n    defer wg.Done() // done\n    result := process(x)\n    log.Printf(\"processed %v\", x)\n    done <- result\n  }()\n}\n",
+		"for _, item := range xs {\n\twg.Add(1)\n\tgo func() {\n\t\tdefer wg.Done()\n\t\tr := process(item)\n\t\tlog.Printf(\"processed %v\", item)\n\t\tdone <- r\n\t}()\n}\n",
+		"for _, x := range xs { wg.Add(1); go func() { defer wg.Done(); result := process(x); log.Printf(\"processed %v\", x); done <- result }() }",
+	}
+	for _, quote := range nearDuplicates {
+		assert.Equal(t, fingerprint, snippet.Fingerprint(quote), quote)
+	}
+
+	different := []string{
+		// identifiers declared outside of the snippet are kept verbatim.
+		"for _, x := range ys {\n\twg.Add(1)\n\tgo func() {\n\t\tdefer wg.Done()\n\t\tresult := process(x)\n\t\tlog.Printf(\"processed %v\", x)\n\t\tdone <- result\n\t}()\n}\n",
+		"for _, x := range xs {\n\twg.Add(1)\n\tgo func() {\n\t\tdefer wg.Done()\n\t\tresult := handle(x)\n\t\tlog.Printf(\"processed %v\", x)\n\t\tdone <- result\n\t}()\n}\n",
+		// as are selectors and package names.
+		"for _, x := range xs {\n\twg.Add(1)\n\tgo func() {\n\t\tdefer wg.Done()\n\t\tresult := process(x)\n\t\tlog.Fatalf(\"processed %v\", x)\n\t\tdone <- result\n\t}()\n}\n",
+		"for _, x := range xs {\n\twg.Add(1)\n\tgo func() {\n\t\tdefer wg.Done()\n\t\tresult := process(x)\n\t\tglog.Printf(\"processed %v\", x)\n\t\tdone <- result\n\t}()\n}\n",
+		"for _, x := range xs {\n\twg.Add(1)\n\tdefer func() {\n\t\tdefer wg.Done()\n\t\tresult := process(x)\n\t\tlog.Printf(\"processed %v\", x)\n\t\tdone <- result\n\t}()\n}\n",
+	}
+	for _, quote := range different {
+		assert.NotEqual(t, fingerprint, snippet.Fingerprint(quote), quote)
+	}
+
+	assert.Nil(t, snippet.Fingerprint("ptrs = append(ptrs, &x)\n"))
+	assert.Nil(t, snippet.Fingerprint("go func() {\n\tprintln(x)\n}()\n"))
+}
+
+func TestFingerprintShortLoop(t *testing.T) {
+	fingerprint := snippet.Fingerprint("for _, x := range xs {\n\tgo func() {\n\t\tuse(x)\n\t}()\n}\n")
+	assert.Len(t, fingerprint, 16)
+	assert.Equal(t, fingerprint, snippet.Fingerprint("for _, item := range xs {\n\tgo func() {\n\t\tuse(item)\n\t}()\n}\n"))
+	assert.NotEqual(t, fingerprint, snippet.Fingerprint("for _, x := range ys {\n\tgo func() {\n\t\tuse(x)\n\t}()\n}\n"))
+}